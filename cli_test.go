@@ -1,10 +1,13 @@
 package main_test
 
 import (
+	"bytes"
+	"encoding/json"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -248,9 +251,32 @@ resource "aws_instance" "sub_web" {
 		t.Errorf("Resource file should be created with recursive flag")
 	}
 
-	if !strings.Contains(outputStr, "2 .tf files") {
+	if !strings.Contains(outputStr, "files=2") {
 		t.Errorf("Should process both root and subdirectory files with recursive flag")
 	}
+
+	// Recursive mode combines subdirectories into the output directory
+	rootOutput := filepath.Join(inputDir, "resource__aws_instance.tf")
+	moduleOutput := filepath.Join(subDir, "resource__aws_instance.tf")
+	if !strings.Contains(outputStr, rootOutput) || strings.Contains(outputStr, moduleOutput) {
+		t.Errorf("Expected subdirectory blocks to be planned into %s, got: %s", rootOutput, outputStr)
+	}
+
+	// --per-directory organizes each directory in place instead
+	cmd = exec.Command(binary, "plan", inputDir, "--recursive", "--per-directory")
+	output, err = cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("CLI execution failed with per-directory flag: %v\nOutput: %s", err, output)
+	}
+	outputStr = string(output)
+	if !strings.Contains(outputStr, moduleOutput) || strings.Contains(outputStr, rootOutput) {
+		t.Errorf("Expected subdirectory blocks to be planned into %s, got: %s", moduleOutput, outputStr)
+	}
+
+	cmd = exec.Command(binary, "plan", inputDir, "--per-directory")
+	if output, err = cmd.CombinedOutput(); err == nil {
+		t.Errorf("Expected error for --per-directory without --recursive, got: %s", output)
+	}
 }
 
 func TestCLIWithConfigFile(t *testing.T) {
@@ -388,7 +414,7 @@ groups:
 		t.Fatalf("CLI execution failed: %v\nOutput: %s", err, output)
 	}
 
-	if !strings.Contains(string(output), "Loading configuration from") {
+	if !strings.Contains(string(output), "Loading configuration file=") {
 		t.Errorf("Expected config auto-detection message, got: %s", output)
 	}
 
@@ -492,3 +518,823 @@ variable "test" {
 		t.Errorf("Expected explanation about combining directories, got: %s", outputStr)
 	}
 }
+
+func TestCLIExplain(t *testing.T) {
+	testDir := createTestDir(t, "explain")
+
+	binary := filepath.Join(testDir, "tf-file-organize")
+	cmd := exec.Command("go", "build", "-o", binary)
+	err := cmd.Run()
+	if err != nil {
+		t.Fatalf("Failed to build binary: %v", err)
+	}
+
+	configFile := filepath.Join(testDir, "config.yaml")
+	configContent := `
+groups:
+  - name: "compute"
+    filename: "compute.tf"
+    patterns:
+      - "aws_instance"
+`
+	err = os.WriteFile(configFile, []byte(configContent), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create config file: %v", err)
+	}
+
+	err = os.WriteFile(filepath.Join(testDir, "main.tf"), []byte(`resource "aws_instance" "web" {}`), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	cmd = exec.Command(binary, "explain", testDir, "resource.aws_instance.web", "--config", configFile)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("CLI execution failed: %v\nOutput: %s", err, output)
+	}
+
+	outputStr := string(output)
+	for _, expected := range []string{"Winning group: compute", "Final filename: compute.tf"} {
+		if !strings.Contains(outputStr, expected) {
+			t.Errorf("Expected output to contain %q, got: %s", expected, outputStr)
+		}
+	}
+
+	cmd = exec.Command(binary, "explain", testDir, "resource.aws_instance.missing")
+	if output, err = cmd.CombinedOutput(); err == nil {
+		t.Errorf("Expected error for unknown address, got: %s", output)
+	}
+}
+
+func TestCLIValidateConfigAgainst(t *testing.T) {
+	testDir := createTestDir(t, "coverage")
+
+	binary := filepath.Join(testDir, "tf-file-organize")
+	cmd := exec.Command("go", "build", "-o", binary)
+	err := cmd.Run()
+	if err != nil {
+		t.Fatalf("Failed to build binary: %v", err)
+	}
+
+	inputDir := filepath.Join(testDir, "terraform")
+	if err = os.MkdirAll(inputDir, 0755); err != nil {
+		t.Fatalf("Failed to create input directory: %v", err)
+	}
+
+	tfContent := `
+resource "aws_vpc" "main" {}
+
+resource "aws_s3_bucket" "logs" {}
+`
+	err = os.WriteFile(filepath.Join(inputDir, "main.tf"), []byte(tfContent), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	configFile := filepath.Join(testDir, "config.yaml")
+	configContent := `
+groups:
+  - name: "network"
+    filename: "network.tf"
+    patterns:
+      - "aws_vpc"
+      - "aws_subnt*"
+`
+	err = os.WriteFile(configFile, []byte(configContent), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create config file: %v", err)
+	}
+
+	cmd = exec.Command(binary, "validate-config", configFile, "--against", inputDir)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("CLI execution failed: %v\nOutput: %s", err, output)
+	}
+
+	outputStr := string(output)
+	for _, expected := range []string{
+		"network → network.tf: 1 blocks",
+		"aws_subnt* (group 'network')",
+		"resource.aws_s3_bucket.logs → resource__aws_s3_bucket.tf",
+	} {
+		if !strings.Contains(outputStr, expected) {
+			t.Errorf("Expected output to contain %q, got: %s", expected, outputStr)
+		}
+	}
+}
+
+func TestCLIInit(t *testing.T) {
+	testDir := createTestDir(t, "init")
+
+	binary := filepath.Join(testDir, "tf-file-organize")
+	cmd := exec.Command("go", "build", "-o", binary)
+	err := cmd.Run()
+	if err != nil {
+		t.Fatalf("Failed to build binary: %v", err)
+	}
+
+	inputDir := filepath.Join(testDir, "terraform")
+	if err = os.MkdirAll(inputDir, 0755); err != nil {
+		t.Fatalf("Failed to create input directory: %v", err)
+	}
+
+	files := map[string]string{
+		"network.tf": "resource \"aws_vpc\" \"main\" {}\n\nresource \"aws_subnet\" \"a\" {}\n",
+		"compute.tf": "resource \"aws_instance\" \"web\" {}\n",
+	}
+	for name, content := range files {
+		if err = os.WriteFile(filepath.Join(inputDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	cmd = exec.Command(binary, "init", inputDir)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("CLI execution failed: %v\nOutput: %s", err, output)
+	}
+	if !strings.Contains(string(output), "current layout is reproduced exactly") {
+		t.Errorf("Expected exact reproduction, got: %s", output)
+	}
+
+	configFile := filepath.Join(inputDir, "tf-file-organize.yaml")
+	cmd = exec.Command(binary, "run", inputDir, "--config", configFile)
+	if output, err = cmd.CombinedOutput(); err != nil {
+		t.Fatalf("CLI execution failed: %v\nOutput: %s", err, output)
+	}
+	for name := range files {
+		if _, statErr := os.Stat(filepath.Join(inputDir, name)); statErr != nil {
+			t.Errorf("Expected %s to be kept by the inferred config: %v", name, statErr)
+		}
+	}
+
+	cmd = exec.Command(binary, "init", inputDir)
+	if output, err = cmd.CombinedOutput(); err == nil {
+		t.Errorf("Expected error when config file already exists, got: %s", output)
+	}
+}
+
+func TestCLIHierarchicalConfigDiscovery(t *testing.T) {
+	testDir := createTestDir(t, "discovery")
+
+	binary := filepath.Join(testDir, "tf-file-organize")
+	cmd := exec.Command("go", "build", "-o", binary)
+	err := cmd.Run()
+	if err != nil {
+		t.Fatalf("Failed to build binary: %v", err)
+	}
+
+	stacksDir := filepath.Join(testDir, "stacks")
+	prodDir := filepath.Join(stacksDir, "prod")
+	appDir := filepath.Join(prodDir, "app")
+	if err = os.MkdirAll(appDir, 0755); err != nil {
+		t.Fatalf("Failed to create directories: %v", err)
+	}
+
+	files := map[string]string{
+		filepath.Join(stacksDir, "tf-file-organize.yaml"): `
+groups:
+  - name: "network"
+    filename: "network.tf"
+    patterns:
+      - "aws_vpc"
+`,
+		filepath.Join(appDir, "tf-file-organize.yaml"): `
+groups:
+  - name: "compute"
+    filename: "compute.tf"
+    patterns:
+      - "aws_instance"
+`,
+		filepath.Join(prodDir, "main.tf"): "resource \"aws_vpc\" \"main\" {}\n\nresource \"aws_instance\" \"web\" {}\n",
+		filepath.Join(appDir, "main.tf"):  "resource \"aws_vpc\" \"app\" {}\n\nresource \"aws_instance\" \"app\" {}\n",
+	}
+	for path, content := range files {
+		if err = os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", path, err)
+		}
+	}
+
+	cmd = exec.Command(binary, "run", prodDir, "--recursive", "--per-directory")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("CLI execution failed: %v\nOutput: %s", err, output)
+	}
+
+	expectedFiles := []string{
+		filepath.Join(prodDir, "network.tf"),
+		filepath.Join(prodDir, "resource__aws_instance.tf"),
+		filepath.Join(appDir, "network.tf"),
+		filepath.Join(appDir, "compute.tf"),
+	}
+	for _, path := range expectedFiles {
+		if _, statErr := os.Stat(path); statErr != nil {
+			t.Errorf("Expected %s to be created: %v\nOutput: %s", path, statErr, output)
+		}
+	}
+}
+
+func TestCLISchema(t *testing.T) {
+	testDir := createTestDir(t, "schema")
+
+	binary := filepath.Join(testDir, "tf-file-organize")
+	cmd := exec.Command("go", "build", "-o", binary)
+	err := cmd.Run()
+	if err != nil {
+		t.Fatalf("Failed to build binary: %v", err)
+	}
+
+	cmd = exec.Command(binary, "schema")
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("CLI execution failed: %v", err)
+	}
+
+	var schema map[string]any
+	if err = json.Unmarshal(output, &schema); err != nil {
+		t.Fatalf("Schema output is not valid JSON: %v\nOutput: %s", err, output)
+	}
+	if _, ok := schema["properties"]; !ok {
+		t.Errorf("Expected schema properties, got: %s", output)
+	}
+}
+
+func TestCLIMigrateConfig(t *testing.T) {
+	testDir := createTestDir(t, "migrate-config")
+
+	binary := filepath.Join(testDir, "tf-file-organize")
+	cmd := exec.Command("go", "build", "-o", binary)
+	err := cmd.Run()
+	if err != nil {
+		t.Fatalf("Failed to build binary: %v", err)
+	}
+
+	configFile := filepath.Join(testDir, "tf-file-organize.yaml")
+	legacy := "# keep this comment\nexclude:\n  - \"*.backup\"\n"
+	if err = os.WriteFile(configFile, []byte(legacy), 0644); err != nil {
+		t.Fatalf("Failed to create config file: %v", err)
+	}
+
+	cmd = exec.Command(binary, "migrate-config", configFile)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("CLI execution failed: %v\nOutput: %s", err, output)
+	}
+
+	content, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatalf("Failed to read migrated config: %v", err)
+	}
+	for _, want := range []string{"# keep this comment", "version: 2", "exclude_files:"} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("Expected migrated config to contain %q, got:\n%s", want, content)
+		}
+	}
+
+	cmd = exec.Command(binary, "migrate-config", configFile)
+	if output, err = cmd.CombinedOutput(); err != nil {
+		t.Fatalf("CLI execution failed: %v\nOutput: %s", err, output)
+	}
+	if !strings.Contains(string(output), "already at version 2") {
+		t.Errorf("Expected second run to be a no-op, got: %s", output)
+	}
+}
+
+func TestCLIPrunesStaleGeneratedFiles(t *testing.T) {
+	testDir := createTestDir(t, "manifest")
+
+	binary := filepath.Join(testDir, "tf-file-organize")
+	cmd := exec.Command("go", "build", "-o", binary)
+	err := cmd.Run()
+	if err != nil {
+		t.Fatalf("Failed to build binary: %v", err)
+	}
+
+	inputDir := filepath.Join(testDir, "src")
+	outputDir := filepath.Join(testDir, "out")
+	if err = os.MkdirAll(inputDir, 0755); err != nil {
+		t.Fatalf("Failed to create input directory: %v", err)
+	}
+	if err = os.WriteFile(filepath.Join(inputDir, "main.tf"), []byte("resource \"aws_vpc\" \"main\" {}\n\nresource \"aws_s3_bucket\" \"logs\" {}\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	configFile := filepath.Join(testDir, "config.yaml")
+	writeConfig := func(filename string) {
+		content := "groups:\n  - name: \"network\"\n    filename: \"" + filename + "\"\n    patterns:\n      - \"aws_vpc\"\n"
+		if err = os.WriteFile(configFile, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create config file: %v", err)
+		}
+	}
+
+	writeConfig("network.tf")
+	cmd = exec.Command(binary, "run", inputDir, "-o", outputDir, "--config", configFile)
+	if output, runErr := cmd.CombinedOutput(); runErr != nil {
+		t.Fatalf("CLI execution failed: %v\nOutput: %s", runErr, output)
+	}
+	if _, err = os.Stat(filepath.Join(outputDir, ".tf-file-organize.lock.json")); err != nil {
+		t.Fatalf("Expected manifest to be written: %v", err)
+	}
+
+	// Renaming the group leaves network.tf stale; hand-edit the bucket file so it is kept
+	writeConfig("vpc.tf")
+	if err = os.WriteFile(filepath.Join(inputDir, "main.tf"), []byte("resource \"aws_vpc\" \"main\" {}\n"), 0644); err != nil {
+		t.Fatalf("Failed to update test file: %v", err)
+	}
+	bucketFile := filepath.Join(outputDir, "resource__aws_s3_bucket.tf")
+	if err = os.WriteFile(bucketFile, []byte("# edited\nresource \"aws_s3_bucket\" \"logs\" {}\n"), 0644); err != nil {
+		t.Fatalf("Failed to edit generated file: %v", err)
+	}
+
+	cmd = exec.Command(binary, "run", inputDir, "-o", outputDir, "--config", configFile)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("CLI execution failed: %v\nOutput: %s", err, output)
+	}
+
+	if _, err = os.Stat(filepath.Join(outputDir, "network.tf")); !os.IsNotExist(err) {
+		t.Errorf("Expected stale network.tf to be removed, output: %s", output)
+	}
+	if _, err = os.Stat(filepath.Join(outputDir, "vpc.tf")); err != nil {
+		t.Errorf("Expected vpc.tf to be created: %v", err)
+	}
+	if _, err = os.Stat(bucketFile); err != nil {
+		t.Errorf("Expected edited stale file to be kept: %v", err)
+	}
+	if !strings.Contains(string(output), "has been modified since") {
+		t.Errorf("Expected warning about edited stale file, got: %s", output)
+	}
+	if !strings.Contains(string(output), "removed=1") {
+		t.Errorf("Expected the pruned file in the removed count, got: %s", output)
+	}
+}
+
+func TestCLISingleFileKeepsManagedFiles(t *testing.T) {
+	testDir := createTestDir(t, "manifest-single-file")
+
+	binary := filepath.Join(testDir, "tf-file-organize")
+	cmd := exec.Command("go", "build", "-o", binary)
+	err := cmd.Run()
+	if err != nil {
+		t.Fatalf("Failed to build binary: %v", err)
+	}
+
+	moduleDir := filepath.Join(testDir, "d")
+	if err = os.MkdirAll(moduleDir, 0755); err != nil {
+		t.Fatalf("Failed to create module directory: %v", err)
+	}
+	main := "variable \"region\" {}\n\nresource \"aws_instance\" \"web\" {}\n\nresource \"aws_s3_bucket\" \"logs\" {}\n"
+	if err = os.WriteFile(filepath.Join(moduleDir, "main.tf"), []byte(main), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	cmd = exec.Command(binary, "run", moduleDir)
+	if output, runErr := cmd.CombinedOutput(); runErr != nil {
+		t.Fatalf("CLI execution failed: %v\nOutput: %s", runErr, output)
+	}
+
+	// Organizing one new file must not prune the files generated from the rest of the module
+	extraFile := filepath.Join(moduleDir, "extra.tf")
+	if err = os.WriteFile(extraFile, []byte("resource \"aws_instance\" \"api\" {}\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	cmd = exec.Command(binary, "run", extraFile)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("CLI execution failed: %v\nOutput: %s", err, output)
+	}
+
+	for _, fileName := range []string{"resource__aws_s3_bucket.tf", "variables.tf"} {
+		if _, err = os.Stat(filepath.Join(moduleDir, fileName)); err != nil {
+			t.Errorf("Expected %s to be kept: %v\nOutput: %s", fileName, err, output)
+		}
+	}
+	content, err := os.ReadFile(filepath.Join(moduleDir, "resource__aws_instance.tf"))
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	for _, address := range []string{`"aws_instance" "web"`, `"aws_instance" "api"`} {
+		if !strings.Contains(string(content), address) {
+			t.Errorf("Expected resource__aws_instance.tf to hold %s, got:\n%s", address, content)
+		}
+	}
+	if _, err = os.Stat(extraFile); !os.IsNotExist(err) {
+		t.Errorf("Expected extra.tf to be removed, got %v", err)
+	}
+	if !strings.Contains(string(output), "removed=1") {
+		t.Errorf("Expected one removed file in the summary, got: %s", output)
+	}
+}
+
+func TestCLIHeaderAndSectionSeparators(t *testing.T) {
+	testDir := createTestDir(t, "header")
+
+	binary := filepath.Join(testDir, "tf-file-organize")
+	cmd := exec.Command("go", "build", "-o", binary)
+	err := cmd.Run()
+	if err != nil {
+		t.Fatalf("Failed to build binary: %v", err)
+	}
+
+	inputDir := filepath.Join(testDir, "src")
+	if err = os.MkdirAll(inputDir, 0755); err != nil {
+		t.Fatalf("Failed to create input directory: %v", err)
+	}
+	source := `resource "aws_security_group" "web" {
+  name = "web"
+}
+
+# Allow HTTPS
+resource "aws_security_group_rule" "https" {
+  from_port = 443
+}
+
+resource "aws_security_group" "db" {
+  name = "db"
+}
+`
+	if err = os.WriteFile(filepath.Join(inputDir, "main.tf"), []byte(source), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	configFile := filepath.Join(testDir, "config.yaml")
+	configContent := `groups:
+  - name: "security"
+    filename: "security.tf"
+    patterns:
+      - "aws_security_group*"
+output:
+  header: "Managed by tf-file-organize; edit placement via tf-file-organize.yaml"
+  section_separators: true
+`
+	if err = os.WriteFile(configFile, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create config file: %v", err)
+	}
+
+	run := func() string {
+		t.Helper()
+		cmd := exec.Command(binary, "run", inputDir, "--config", configFile)
+		if output, runErr := cmd.CombinedOutput(); runErr != nil {
+			t.Fatalf("CLI execution failed: %v\nOutput: %s", runErr, output)
+		}
+		content, readErr := os.ReadFile(filepath.Join(inputDir, "security.tf"))
+		if readErr != nil {
+			t.Fatalf("Failed to read security.tf: %v", readErr)
+		}
+		return string(content)
+	}
+
+	first := run()
+	expected := `# Managed by tf-file-organize; edit placement via tf-file-organize.yaml
+
+# --- aws_security_group ---
+
+resource "aws_security_group" "db" {
+  name = "db"
+}
+resource "aws_security_group" "web" {
+  name = "web"
+}
+
+# --- aws_security_group_rule ---
+
+# Allow HTTPS
+
+resource "aws_security_group_rule" "https" {
+  from_port = 443
+}`
+	if first != expected {
+		t.Errorf("Expected security.tf:\n%s\ngot:\n%s", expected, first)
+	}
+
+	// Reorganizing the generated file keeps a single header and set of separators
+	if second := run(); second != first {
+		t.Errorf("Expected rerun to leave security.tf unchanged, got:\n%s", second)
+	}
+}
+
+func TestCLILicenseHeader(t *testing.T) {
+	testDir := createTestDir(t, "license")
+
+	binary := filepath.Join(testDir, "tf-file-organize")
+	cmd := exec.Command("go", "build", "-o", binary)
+	err := cmd.Run()
+	if err != nil {
+		t.Fatalf("Failed to build binary: %v", err)
+	}
+
+	inputDir := filepath.Join(testDir, "src")
+	if err = os.MkdirAll(inputDir, 0755); err != nil {
+		t.Fatalf("Failed to create input directory: %v", err)
+	}
+	license := "# Copyright 2024 Example Corp\n# SPDX-License-Identifier: MPL-2.0\n"
+	files := map[string]string{
+		"main.tf": license + `
+# Primary network
+resource "aws_vpc" "main" {
+  cidr_block = "10.0.0.0/16"
+}
+
+variable "region" {
+  type = string
+}
+`,
+		"outputs.tf": license + `
+output "vpc_id" {
+  value = aws_vpc.main.id
+}
+`,
+	}
+	for name, content := range files {
+		if err = os.WriteFile(filepath.Join(inputDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	configFile := filepath.Join(testDir, "config.yaml")
+	if err = os.WriteFile(configFile, []byte("output:\n  license_pattern: \"SPDX-License-Identifier\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to create config file: %v", err)
+	}
+
+	run := func() map[string]string {
+		t.Helper()
+		cmd := exec.Command(binary, "run", inputDir, "--config", configFile)
+		if output, runErr := cmd.CombinedOutput(); runErr != nil {
+			t.Fatalf("CLI execution failed: %v\nOutput: %s", runErr, output)
+		}
+		contents := make(map[string]string)
+		for _, name := range []string{"resource__aws_vpc.tf", "variables.tf", "outputs.tf"} {
+			content, readErr := os.ReadFile(filepath.Join(inputDir, name))
+			if readErr != nil {
+				t.Fatalf("Failed to read %s: %v", name, readErr)
+			}
+			contents[name] = string(content)
+		}
+		return contents
+	}
+
+	first := run()
+	for name, content := range first {
+		if !strings.HasPrefix(content, license+"\n") || strings.Count(content, "SPDX-License-Identifier") != 1 {
+			t.Errorf("Expected %s to start with the license header once, got:\n%s", name, content)
+		}
+	}
+	if !strings.Contains(first["resource__aws_vpc.tf"], "# Primary network\n") {
+		t.Errorf("Expected block comment to stay with its block, got:\n%s", first["resource__aws_vpc.tf"])
+	}
+
+	second := run()
+	for name, content := range first {
+		if second[name] != content {
+			t.Errorf("Expected rerun to leave %s unchanged, got:\n%s", name, second[name])
+		}
+	}
+}
+
+func TestCLILogging(t *testing.T) {
+	testDir := createTestDir(t, "logging")
+
+	binary := filepath.Join(testDir, "tf-file-organize")
+	cmd := exec.Command("go", "build", "-o", binary)
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to build binary: %v", err)
+	}
+
+	inputDir := filepath.Join(testDir, "terraform")
+	if err := os.MkdirAll(inputDir, 0755); err != nil {
+		t.Fatalf("Failed to create input directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(inputDir, "main.tf"), []byte(variableContent), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(inputDir, "broken.tf"), []byte(`resource "aws_vpc" {`), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	// Quiet mode keeps warnings only
+	cmd = exec.Command(binary, "plan", inputDir, "--quiet")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("CLI execution failed: %v\nOutput: %s", err, output)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(output)), "\n"); len(lines) != 1 || !strings.HasPrefix(lines[0], "Warning: failed to parse file") {
+		t.Errorf("Expected only the parse warning, got: %s", output)
+	}
+
+	// JSON events go to stderr, one per line
+	cmd = exec.Command(binary, "plan", inputDir, "--log-format", "json", "--verbose")
+	stdout, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("CLI execution failed: %v", err)
+	}
+	if len(stdout) != 0 {
+		t.Errorf("Expected no output on stdout, got: %s", stdout)
+	}
+	cmd = exec.Command(binary, "plan", inputDir, "--log-format", "json", "--verbose")
+	output, err = cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("CLI execution failed: %v\nOutput: %s", err, output)
+	}
+	levels := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		var event map[string]any
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("Expected JSON event, got %q: %v", line, err)
+		}
+		levels[event["level"].(string)] = true
+		if event["msg"] == "failed to parse file" && event["path"] != filepath.Join(inputDir, "broken.tf") {
+			t.Errorf("Expected the warning to name the file, got %v", event)
+		}
+	}
+	for _, level := range []string{"DEBUG", "INFO", "WARN"} {
+		if !levels[level] {
+			t.Errorf("Expected %s events, got: %s", level, output)
+		}
+	}
+
+	cmd = exec.Command(binary, "plan", inputDir, "--log-format", "xml")
+	if output, err = cmd.CombinedOutput(); err == nil {
+		t.Errorf("Expected error for unknown log format, got: %s", output)
+	}
+}
+
+func TestCLIStreaming(t *testing.T) {
+	testDir, err := filepath.Abs(createTestDir(t, "streaming"))
+	if err != nil {
+		t.Fatalf("Failed to resolve test directory: %v", err)
+	}
+
+	binary := filepath.Join(testDir, "tf-file-organize")
+	cmd := exec.Command("go", "build", "-o", binary)
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to build binary: %v", err)
+	}
+
+	tfContent := `resource "aws_vpc" "main" {
+  cidr_block = "10.0.0.0/16"
+}
+` + variableContent
+
+	// run - reads stdin and writes the organized files to stdout
+	cmd = exec.Command(binary, "run", "-")
+	cmd.Dir = testDir
+	cmd.Stdin = strings.NewReader(tfContent)
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("CLI execution failed: %v", err)
+	}
+	expected := `-- resource__aws_vpc.tf --
+resource "aws_vpc" "main" {
+  cidr_block = "10.0.0.0/16"
+}
+-- variables.tf --
+variable "region" {
+  type = string
+}
+`
+	if string(output) != expected {
+		t.Errorf("Expected stream:\n%s\ngot:\n%s", expected, output)
+	}
+	if entries, _ := os.ReadDir(testDir); len(entries) != 1 {
+		t.Errorf("Expected stdin mode not to write files, got %d entries", len(entries))
+	}
+
+	cmd = exec.Command(binary, "run", "-", "--output-dir", "out")
+	cmd.Dir = testDir
+	cmd.Stdin = strings.NewReader(tfContent)
+	if output, err := cmd.CombinedOutput(); err == nil {
+		t.Errorf("Expected error for --output-dir with stdin, got: %s", output)
+	}
+
+	// A document that cannot be parsed, or holds no blocks, fails with no stream
+	for _, input := range []string{`resource "x" {`, "# nothing to organize\n"} {
+		cmd = exec.Command(binary, "run", "-")
+		cmd.Dir = testDir
+		cmd.Stdin = strings.NewReader(input)
+		output, err := cmd.Output()
+		if err == nil {
+			t.Errorf("Expected error for stdin %q", input)
+		}
+		if len(output) != 0 {
+			t.Errorf("Expected no stream for stdin %q, got: %s", input, output)
+		}
+	}
+
+	// plan --stdout prints the content that would be written
+	inputDir := filepath.Join(testDir, "terraform")
+	if err := os.MkdirAll(inputDir, 0755); err != nil {
+		t.Fatalf("Failed to create input directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(inputDir, "main.tf"), []byte(tfContent), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	cmd = exec.Command(binary, "plan", "terraform", "--stdout")
+	cmd.Dir = testDir
+	output, err = cmd.Output()
+	if err != nil {
+		t.Fatalf("CLI execution failed: %v", err)
+	}
+	if !strings.HasPrefix(string(output), "-- terraform/resource__aws_vpc.tf --\n") ||
+		!strings.Contains(string(output), "-- terraform/variables.tf --\nvariable \"region\" {") {
+		t.Errorf("Expected planned files on stdout, got:\n%s", output)
+	}
+	if _, err := os.Stat(filepath.Join(inputDir, "variables.tf")); !os.IsNotExist(err) {
+		t.Error("Expected plan --stdout not to write files")
+	}
+
+	// plan --normalize-blocks previews the reordering run --normalize-blocks writes
+	unordered := "resource \"aws_instance\" \"web\" {\n  depends_on = [aws_vpc.main]\n  ami        = \"ami-123\"\n  count      = 2\n}\n"
+	if err := os.WriteFile(filepath.Join(inputDir, "main.tf"), []byte(unordered), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	cmd = exec.Command(binary, "plan", "terraform", "--stdout", "--normalize-blocks")
+	cmd.Dir = testDir
+	output, err = cmd.Output()
+	if err != nil {
+		t.Fatalf("CLI execution failed: %v", err)
+	}
+	if !strings.Contains(string(output), "resource \"aws_instance\" \"web\" {\n  count = 2\n\n  ami = \"ami-123\"\n\n  depends_on = [aws_vpc.main]\n}") {
+		t.Errorf("Expected normalized block on stdout, got:\n%s", output)
+	}
+}
+
+// syncBuffer collects the output of a running command.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// waitForOutput waits until buf contains expected.
+func waitForOutput(t *testing.T, buf *syncBuffer, expected string) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !strings.Contains(buf.String(), expected) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %q, got:\n%s", expected, buf.String())
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestCLIWatch(t *testing.T) {
+	testDir := createTestDir(t, "watch")
+
+	binary := filepath.Join(testDir, "tf-file-organize")
+	cmd := exec.Command("go", "build", "-o", binary)
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to build binary: %v", err)
+	}
+
+	inputDir := filepath.Join(testDir, "input")
+	if err := os.MkdirAll(inputDir, 0755); err != nil {
+		t.Fatalf("Failed to create input directory: %v", err)
+	}
+	mainFile := filepath.Join(inputDir, "main.tf")
+	if err := os.WriteFile(mainFile, []byte(variableContent), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+
+	var stdout, stderr syncBuffer
+	cmd = exec.Command(binary, "watch", inputDir, "--debounce", "100ms")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Failed to start watch: %v", err)
+	}
+	t.Cleanup(func() { _ = cmd.Process.Kill() })
+
+	waitForOutput(t, &stdout, "Moved variable.region: main.tf -> variables.tf")
+	waitForOutput(t, &stderr, "Watching for changes")
+
+	if err := os.WriteFile(mainFile, []byte("output \"region\" {\n  value = var.region\n}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	waitForOutput(t, &stdout, "Moved output.region: main.tf -> outputs.tf")
+
+	// The files written by the second run must not trigger a third one
+	time.Sleep(time.Second)
+	if count := strings.Count(stderr.String(), "Detected changes"); count != 1 {
+		t.Errorf("Expected exactly one detected change, got %d:\n%s", count, stderr.String())
+	}
+
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		t.Fatalf("Failed to interrupt watch: %v", err)
+	}
+	if err := cmd.Wait(); err != nil {
+		t.Errorf("Expected watch to exit cleanly on interrupt: %v\n%s", err, stderr.String())
+	}
+	if _, err := os.Stat(filepath.Join(inputDir, "outputs.tf")); err != nil {
+		t.Errorf("Expected outputs.tf to be written: %v", err)
+	}
+}
@@ -1,48 +1,38 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/tomoya-namekawa/tf-file-organize/internal/usecase"
 	"github.com/tomoya-namekawa/tf-file-organize/internal/validation"
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/organize"
 )
 
-// executeOrganizeFiles validates inputs and executes the organize files usecase
-func executeOrganizeFiles(inputPath, outputDir, configFile string, recursive, dryRun, backup bool) error {
+// executeOrganizeFiles validates inputs and organizes files, logging progress
+func executeOrganizeFiles(ctx context.Context, opts organize.Options) (*organize.Result, error) {
 	// Validate all inputs first
-	if err := validation.ValidateInputPath(inputPath); err != nil {
-		return fmt.Errorf("invalid input path: %w", err)
+	if err := validation.ValidateInputPath(opts.InputPath); err != nil {
+		return nil, fmt.Errorf("invalid input path: %w", err)
 	}
 
-	if err := validation.ValidateOutputPath(outputDir); err != nil {
-		return err
+	if err := validation.ValidateOutputPath(opts.OutputDir); err != nil {
+		return nil, err
 	}
 
-	if err := validation.ValidateConfigPath(configFile); err != nil {
-		return err
-	}
-
-	// Validate flag combinations
-	if err := validation.ValidateFlagCombination(outputDir, recursive); err != nil {
-		return err
-	}
-
-	// Create usecase request
-	req := &usecase.OrganizeFilesRequest{
-		InputPath:  inputPath,
-		OutputDir:  outputDir,
-		ConfigFile: configFile,
-		DryRun:     dryRun,
-		Recursive:  recursive,
-		Backup:     backup,
+	if err := validation.ValidateConfigPath(opts.ConfigFile); err != nil {
+		return nil, err
 	}
 
 	// Execute usecase
-	uc := usecase.NewOrganizeFilesUsecase()
-	_, err := uc.Execute(req)
-	if err != nil {
-		return err
-	}
+	opts.Logger = logger
+	return organize.Organize(ctx, opts)
+}
 
-	return nil
+// newUsecase creates a usecase logging through the logger set up from the
+// global flags.
+func newUsecase() *usecase.OrganizeFilesUsecase {
+	uc := usecase.NewOrganizeFilesUsecase()
+	uc.SetLogger(logger)
+	return uc
 }
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tomoya-namekawa/tf-file-organize/internal/splitter"
+	"github.com/tomoya-namekawa/tf-file-organize/internal/usecase"
+	"github.com/tomoya-namekawa/tf-file-organize/internal/validation"
+)
+
+var (
+	explainConfigFile string
+	explainRecursive  bool
+)
+
+// explainCmd represents the explain command
+var explainCmd = &cobra.Command{
+	Use:   "explain <input-path> <block-address>",
+	Short: "Explain why a block is placed in its output file",
+	Long: `Show how a single block is matched against the configuration.
+
+The block address uses the same form as sub-type patterns, for example
+resource.aws_instance.web, data.aws_ami.ubuntu, module.vpc or variable.region.
+Addresses without a block type prefix are treated as resources.
+
+The output lists every match candidate, every group pattern tested against it,
+the winning group, the exclude_files checks and the final filename.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runExplain(cmd.Context(), args[0], args[1]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(explainCmd)
+
+	explainCmd.Flags().StringVarP(&explainConfigFile, "config", "c", "", "Configuration file for custom grouping rules")
+	explainCmd.Flags().BoolVarP(&explainRecursive, "recursive", "r", false, "Process directories recursively")
+}
+
+func runExplain(ctx context.Context, inputPath, address string) error {
+	if err := validation.ValidateInputPath(inputPath); err != nil {
+		return fmt.Errorf("invalid input path: %w", err)
+	}
+
+	if err := validation.ValidateConfigPath(explainConfigFile); err != nil {
+		return err
+	}
+
+	uc := newUsecase()
+	resp, err := uc.ExplainBlock(ctx, &usecase.ExplainBlockRequest{
+		InputPath:  inputPath,
+		ConfigFile: explainConfigFile,
+		Recursive:  explainRecursive,
+		Address:    address,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, explanation := range resp.Explanations {
+		printExplanation(explanation)
+	}
+	return nil
+}
+
+func printExplanation(e *splitter.Explanation) {
+	fmt.Printf("\n🔍 %s (%s)\n", e.Address, e.SourceFile)
+
+	fmt.Println("\nCandidates (in priority order):")
+	for i, candidate := range e.Candidates {
+		fmt.Printf("  %d. %s\n", i+1, candidate)
+	}
+
+	if len(e.PatternChecks) > 0 {
+		fmt.Println("\nPatterns tested:")
+		for _, check := range e.PatternChecks {
+			mark := "✗"
+			if check.Matched {
+				mark = "✓"
+			}
+			fmt.Printf("  %s %-40s group %-20s pattern %s\n", mark, check.Candidate, check.Group, check.Pattern)
+		}
+	}
+
+	fmt.Println()
+	if e.MatchedGroup == "" {
+		fmt.Println("Winning group: none (default naming applies)")
+	} else {
+		fmt.Printf("Winning group: %s (candidate %s, pattern %s)\n", e.MatchedGroup, e.MatchedCandidate, e.MatchedPattern)
+	}
+
+	if len(e.ExcludeChecks) > 0 {
+		fmt.Println("\nExclusion checks:")
+		for _, check := range e.ExcludeChecks {
+			mark := "✗"
+			if check.Matched {
+				mark = "✓"
+			}
+			fmt.Printf("  %s %s against %s\n", mark, check.Filename, check.Pattern)
+		}
+		if e.Excluded {
+			fmt.Println("  Group filename is excluded; falling back to per-type file")
+		}
+	}
+
+	if e.Folded {
+		fmt.Printf("\nGroup file %s is below min_blocks and was folded\n", e.GroupFileName)
+	}
+	fmt.Printf("\nFinal filename: %s\n", e.FileName)
+}
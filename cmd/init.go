@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tomoya-namekawa/tf-file-organize/internal/usecase"
+	"github.com/tomoya-namekawa/tf-file-organize/internal/validation"
+)
+
+var (
+	initConfigFile string
+	initForce      bool
+	initDryRun     bool
+)
+
+// initCmd represents the init command
+var initCmd = &cobra.Command{
+	Use:   "init <input-dir>",
+	Short: "Generate a starter configuration from the current file layout",
+	Long: `Infer a configuration file from hand-organized Terraform files.
+
+The Terraform files in the directory are parsed and groups are generated so that
+the first 'run' reproduces the current placement as closely as possible:
+
+- Blocks already in their default file need no pattern
+- Types kept in a single file get a type pattern, collapsed into wildcards
+  (e.g. resource.aws_security_group*) when that captures no other file's types
+- Types split across files get one pattern per block
+
+The result is written to tf-file-organize.yaml in the input directory.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runInit(cmd.Context(), args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(initCmd)
+
+	initCmd.Flags().StringVarP(&initConfigFile, "config", "c", "", "Path of the configuration file to write (default: <input-dir>/tf-file-organize.yaml)")
+	initCmd.Flags().BoolVar(&initForce, "force", false, "Overwrite an existing configuration file")
+	initCmd.Flags().BoolVar(&initDryRun, "dry-run", false, "Print the inferred configuration instead of writing it")
+}
+
+func runInit(ctx context.Context, inputPath string) error {
+	if err := validation.ValidateInputPath(inputPath); err != nil {
+		return fmt.Errorf("invalid input path: %w", err)
+	}
+
+	if initConfigFile != "" {
+		if err := validation.ValidatePath(initConfigFile); err != nil {
+			return fmt.Errorf("invalid config file path: %w", err)
+		}
+	}
+
+	uc := newUsecase()
+	resp, err := uc.InitConfig(ctx, &usecase.InitConfigRequest{
+		InputPath:  inputPath,
+		ConfigFile: initConfigFile,
+		Force:      initForce,
+		DryRun:     initDryRun,
+	})
+	if err != nil {
+		return err
+	}
+
+	if initDryRun {
+		fmt.Println()
+		fmt.Print(string(resp.Content))
+		fmt.Println()
+	} else {
+		fmt.Printf("Created configuration file: %s\n", resp.ConfigFile)
+	}
+
+	fmt.Printf("Inferred %d groups for %d blocks", len(resp.Config.Groups), resp.TotalBlocks)
+	if resp.Moves == 0 {
+		fmt.Println(" (current layout is reproduced exactly)")
+	} else {
+		fmt.Printf(" (%d blocks would still move on 'run')\n", resp.Moves)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tomoya-namekawa/tf-file-organize/internal/config"
+	"github.com/tomoya-namekawa/tf-file-organize/internal/validation"
+)
+
+var migrateDryRun bool
+
+// migrateConfigCmd represents the migrate-config command
+var migrateConfigCmd = &cobra.Command{
+	Use:   "migrate-config <config-file>",
+	Short: "Rewrite a configuration file in the newest format",
+	Long: `Upgrade a configuration file written for an older format version.
+
+Older files are already upgraded in memory when loaded; this command rewrites
+the file so the upgrade is permanent:
+
+- 'exclude' becomes 'exclude_files'
+- 'overrides' entries become group patterns
+- 'version' is set to the current format version
+
+Comments and the layout of unchanged sections are preserved.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runMigrateConfig(args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(migrateConfigCmd)
+
+	migrateConfigCmd.Flags().BoolVar(&migrateDryRun, "dry-run", false, "Print the migrated configuration instead of writing it")
+}
+
+func runMigrateConfig(configPath string) error {
+	if err := validation.ValidateConfigPath(configPath); err != nil {
+		return err
+	}
+
+	stat, err := os.Stat(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to access config file: %w", err)
+	}
+
+	data, err := os.ReadFile(configPath) //nolint:gosec // configPath is validated above
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	migrated, notes, err := config.MigrateDocument(data)
+	if err != nil {
+		return fmt.Errorf("failed to migrate %s: %w", configPath, err)
+	}
+	if len(notes) == 0 {
+		fmt.Printf("%s is already at version %d\n", configPath, config.CurrentVersion)
+		return nil
+	}
+
+	if migrateDryRun {
+		fmt.Print(string(migrated))
+	} else {
+		if err := os.WriteFile(configPath, migrated, stat.Mode().Perm()); err != nil {
+			return fmt.Errorf("failed to write config file: %w", err)
+		}
+		fmt.Printf("Migrated %s to version %d:\n", configPath, config.CurrentVersion)
+	}
+	for _, note := range notes {
+		fmt.Printf("  - %s\n", note)
+	}
+	return nil
+}
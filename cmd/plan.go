@@ -1,10 +1,13 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 
 	"github.com/spf13/cobra"
+
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/organize"
 )
 
 var (
@@ -12,6 +15,12 @@ var (
 	planOutputDir  string
 	planConfigFile string
 	planRecursive  bool
+	planPerDir     bool
+	planNormalize  bool
+	planStdout     bool
+	planStream     string
+	planSince      string
+	planJobs       int
 )
 
 // planCmd represents the plan command
@@ -24,11 +33,19 @@ This is equivalent to 'run --dry-run' but as a dedicated subcommand.
 Shows which files would be created and how blocks would be organized.
 
 Input can be either a single .tf file or a directory containing .tf files.
-By default, only files in the specified directory are processed. Use -r for recursive processing.`,
+By default, only files in the specified directory are processed. Use -r for recursive processing.
+With -r --per-directory, each directory containing .tf files is organized in place
+as its own module instead of combining subdirectories into the output directory.
+
+Without --config, configuration files are discovered by walking up from the input
+path to the repository root; nearer configs extend the ones above them.
+
+With --stdout, the content of every file that would be created or updated is
+written to stdout as a txtar (default) or tar stream.`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		planInputFile = args[0]
-		if err := runPlan(); err != nil {
+		if err := runPlan(cmd.Context()); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
@@ -42,8 +59,31 @@ func init() {
 	planCmd.Flags().StringVarP(&planOutputDir, "output-dir", "o", "", "Output directory for split files (default: same as input path)")
 	planCmd.Flags().StringVarP(&planConfigFile, "config", "c", "", "Configuration file for custom grouping rules")
 	planCmd.Flags().BoolVarP(&planRecursive, "recursive", "r", false, "Process directories recursively")
+	planCmd.Flags().BoolVar(&planPerDir, "per-directory", false, "With --recursive, organize each directory with .tf files in place as its own module")
+	planCmd.Flags().BoolVar(&planNormalize, "normalize-blocks", false, "Reorder attributes inside blocks into canonical order (meta-arguments first, lifecycle and depends_on last)")
+	planCmd.Flags().BoolVar(&planStdout, "stdout", false, "Print the content of the files that would be written")
+	planCmd.Flags().StringVar(&planStream, "stream-format", streamFormatTxtar, "Format of the files printed with --stdout: txtar or tar")
+	planCmd.Flags().StringVar(&planSince, "since", "", "Only plan modules with .tf files changed since this git ref (e.g. origin/main)")
+	planCmd.Flags().IntVarP(&planJobs, "jobs", "j", 0, "Number of files parsed and modules planned at the same time (default: number of CPUs)")
 }
 
-func runPlan() error {
-	return executeOrganizeFiles(planInputFile, planOutputDir, planConfigFile, planRecursive, true, false)
+func runPlan(ctx context.Context) error {
+	if err := validateStreamFormat(planStream); err != nil {
+		return err
+	}
+	result, err := executeOrganizeFiles(ctx, organize.Options{
+		InputPath:       planInputFile,
+		OutputDir:       planOutputDir,
+		ConfigFile:      planConfigFile,
+		Recursive:       planRecursive,
+		PerDirectory:    planPerDir,
+		NormalizeBlocks: planNormalize,
+		DryRun:          true,
+		Since:           planSince,
+		Jobs:            planJobs,
+	})
+	if err != nil || !planStdout {
+		return err
+	}
+	return writePlannedFiles(os.Stdout, planStream, result.Operations)
 }
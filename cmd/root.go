@@ -1,11 +1,27 @@
 package cmd
 
 import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
 	"github.com/spf13/cobra"
 
+	"github.com/tomoya-namekawa/tf-file-organize/internal/logging"
 	"github.com/tomoya-namekawa/tf-file-organize/internal/version"
 )
 
+var (
+	logQuiet   bool
+	logVerbose bool
+	logFormat  string
+
+	// logger receives progress and warning events; set up before any command runs
+	logger = slog.Default()
+)
+
 var rootCmd = &cobra.Command{
 	Use:     "tf-file-organize",
 	Short:   "Organize Terraform files by resource type",
@@ -14,12 +30,46 @@ var rootCmd = &cobra.Command{
 Each resource type will be placed in its own file following naming conventions.
 
 Available commands:
+  init            Generate a starter configuration from the current layout
   run             Organize Terraform files
   plan            Show what would be done without actually creating files
+  watch           Organize Terraform files whenever they change
   validate-config Validate configuration file
+  migrate-config  Rewrite a configuration file in the newest format
+  explain         Explain why a block is placed in its output file
+  schema          Print the JSON Schema for the configuration file
   version         Show version information
 
 Use "tf-file-organize <command> --help" for more information about a command.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return setupLogger()
+	},
+}
+
+func init() {
+	rootCmd.PersistentFlags().BoolVarP(&logQuiet, "quiet", "q", false, "Only report warnings and errors")
+	rootCmd.PersistentFlags().BoolVar(&logVerbose, "verbose", false, "Report every file parsed and skipped")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", logging.FormatText, "Log format: text or json")
+	rootCmd.MarkFlagsMutuallyExclusive("quiet", "verbose")
+}
+
+// setupLogger builds the logger from the global flags. Events go to stderr so
+// they never mix with command output.
+func setupLogger() error {
+	level := slog.LevelInfo
+	switch {
+	case logQuiet:
+		level = slog.LevelWarn
+	case logVerbose:
+		level = slog.LevelDebug
+	}
+
+	l, err := logging.New(os.Stderr, logging.Options{Level: level, Format: logFormat})
+	if err != nil {
+		return err
+	}
+	logger = l
+	return nil
 }
 
 // Execute runs the root command and handles CLI argument parsing.
@@ -27,5 +77,26 @@ func Execute() error {
 	// Enable version flag
 	rootCmd.SetVersionTemplate("{{.Version}}\n")
 
-	return rootCmd.Execute()
+	ctx, cancel := signalContext()
+	defer cancel()
+	return rootCmd.ExecuteContext(ctx)
+}
+
+// signalContext returns a context canceled by the first SIGINT or SIGTERM, so
+// that a run can finish or roll back the module it is writing. Later signals
+// get their default behavior and exit immediately.
+func signalContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-signals:
+			logger.Warn("Interrupted: finishing or rolling back the current module; interrupt again to exit immediately", "signal", sig.String())
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(signals)
+	}()
+	return ctx, cancel
 }
@@ -1,10 +1,13 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 
 	"github.com/spf13/cobra"
+
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/organize"
 )
 
 var (
@@ -12,22 +15,45 @@ var (
 	runOutputDir  string
 	runConfigFile string
 	runRecursive  bool
+	runPerDir     bool
 	runBackup     bool
+	runForce      bool
+	runNormalize  bool
+	runStream     string
+	runGit        bool
+	runAllowDirty bool
+	runSince      string
+	runJobs       int
 )
 
 // runCmd represents the run command
 var runCmd = &cobra.Command{
-	Use:   "run <input-path>",
+	Use:   "run <input-path | ->",
 	Short: "Organize Terraform files by resource type",
 	Long: `A CLI tool to split Terraform files into separate files organized by resource type.
 Each resource type will be placed in its own file following naming conventions.
 
 Input can be either a single .tf file or a directory containing .tf files.
-By default, only files in the specified directory are processed. Use -r for recursive processing.`,
+By default, only files in the specified directory are processed. Use -r for recursive processing.
+With -r --per-directory, each directory containing .tf files is organized in place
+as its own module instead of combining subdirectories into the output directory.
+
+Without --config, configuration files are discovered by walking up from the input
+path to the repository root; nearer configs extend the ones above them.
+
+With "-" as the input path, a single Terraform document is read from stdin and
+the organized files are written to stdout as a txtar (default) or tar stream,
+without touching the disk. The configuration then comes from --config or the
+current directory.
+
+With --git, each source file is moved with 'git mv' to the output file receiving
+most of its blocks before the files are rewritten, so 'git log --follow' and
+blame keep working, and all changes are staged. The worktree must be clean
+unless --allow-dirty is given.`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		runInputFile = args[0]
-		if err := runOrganize(); err != nil {
+		if err := runOrganize(cmd.Context()); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
@@ -41,9 +67,35 @@ func init() {
 	runCmd.Flags().StringVarP(&runOutputDir, "output-dir", "o", "", "Output directory for split files (default: same as input path)")
 	runCmd.Flags().StringVarP(&runConfigFile, "config", "c", "", "Configuration file for custom grouping rules")
 	runCmd.Flags().BoolVarP(&runRecursive, "recursive", "r", false, "Process directories recursively")
+	runCmd.Flags().BoolVar(&runPerDir, "per-directory", false, "With --recursive, organize each directory with .tf files in place as its own module")
 	runCmd.Flags().BoolVar(&runBackup, "backup", false, "Backup original files to 'backup' subdirectory before organizing")
+	runCmd.Flags().BoolVar(&runForce, "force", false, "Overwrite existing output files whose content cannot be merged")
+	runCmd.Flags().BoolVar(&runNormalize, "normalize-blocks", false, "Reorder attributes inside blocks into canonical order (meta-arguments first, lifecycle and depends_on last)")
+	runCmd.Flags().StringVar(&runStream, "stream-format", streamFormatTxtar, "Format of the files written to stdout when reading from stdin: txtar or tar")
+	runCmd.Flags().BoolVar(&runGit, "git", false, "Move source files with 'git mv' to their main output file and stage the changes")
+	runCmd.Flags().BoolVar(&runAllowDirty, "allow-dirty", false, "Allow --git on a worktree with uncommitted changes")
+	runCmd.Flags().StringVar(&runSince, "since", "", "Only organize modules with .tf files changed since this git ref (e.g. origin/main)")
+	runCmd.Flags().IntVarP(&runJobs, "jobs", "j", 0, "Number of files parsed and modules planned at the same time (default: number of CPUs)")
 }
 
-func runOrganize() error {
-	return executeOrganizeFiles(runInputFile, runOutputDir, runConfigFile, runRecursive, false, runBackup)
+func runOrganize(ctx context.Context) error {
+	opts := organize.Options{
+		InputPath:       runInputFile,
+		OutputDir:       runOutputDir,
+		ConfigFile:      runConfigFile,
+		Recursive:       runRecursive,
+		PerDirectory:    runPerDir,
+		Backup:          runBackup,
+		Force:           runForce,
+		NormalizeBlocks: runNormalize,
+		Git:             runGit,
+		AllowDirty:      runAllowDirty,
+		Since:           runSince,
+		Jobs:            runJobs,
+	}
+	if runInputFile == stdinInput {
+		return organizeStdin(ctx, os.Stdin, os.Stdout, runStream, opts)
+	}
+	_, err := executeOrganizeFiles(ctx, opts)
+	return err
 }
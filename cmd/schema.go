@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tomoya-namekawa/tf-file-organize/internal/config"
+	"github.com/tomoya-namekawa/tf-file-organize/internal/validation"
+)
+
+var schemaOutput string
+
+// schemaCmd represents the schema command
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema for the configuration file",
+	Long: `Print the JSON Schema describing tf-file-organize configuration files.
+
+Editors using the YAML language server can autocomplete and lint configuration
+files against it by adding a modeline to the top of the file:
+
+  # yaml-language-server: $schema=./tf-file-organize.schema.json
+
+The same schema is used by the tool itself to validate configuration files.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runSchema(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+
+	schemaCmd.Flags().StringVarP(&schemaOutput, "output", "o", "", "Write the schema to a file instead of stdout")
+}
+
+func runSchema() error {
+	if schemaOutput == "" {
+		fmt.Print(string(config.Schema()))
+		return nil
+	}
+
+	if err := validation.ValidatePath(schemaOutput); err != nil {
+		return fmt.Errorf("invalid output path: %w", err)
+	}
+	if err := os.WriteFile(schemaOutput, config.Schema(), 0600); err != nil {
+		return fmt.Errorf("failed to write schema: %w", err)
+	}
+	fmt.Printf("Wrote configuration schema to %s\n", schemaOutput)
+	return nil
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/tomoya-namekawa/tf-file-organize/internal/config"
+	"github.com/tomoya-namekawa/tf-file-organize/internal/validation"
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/filesystem"
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/organize"
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/types"
+)
+
+// Formats of the file stream written by 'run -' and 'plan --stdout'
+const (
+	streamFormatTxtar = "txtar"
+	streamFormatTar   = "tar"
+)
+
+const (
+	// stdinInput is the input path that reads a Terraform document from stdin
+	stdinInput = "-"
+	// stdinFileName names the document read from stdin
+	stdinFileName = "stdin.tf"
+	// maxStdinSize limits the document read from stdin
+	maxStdinSize = 64 * 1024 * 1024
+)
+
+// organizeStdin organizes the Terraform document read from r in memory and
+// writes the resulting files to w as a stream. Without a config file, the
+// configuration of the current directory is used. A document that cannot be
+// parsed or holds no blocks is an error.
+func organizeStdin(ctx context.Context, r io.Reader, w io.Writer, format string, opts organize.Options) error {
+	if err := validateStreamFormat(format); err != nil {
+		return err
+	}
+	if opts.OutputDir != "" || opts.Recursive || opts.Backup || opts.Git || opts.Since != "" {
+		return fmt.Errorf("--output-dir, --recursive, --backup, --git and --since cannot be used when reading from stdin")
+	}
+
+	content, err := io.ReadAll(io.LimitReader(r, maxStdinSize+1))
+	if err != nil {
+		return fmt.Errorf("failed to read stdin: %w", err)
+	}
+	if len(content) > maxStdinSize {
+		return fmt.Errorf("input from stdin too large (max %d bytes)", maxStdinSize)
+	}
+
+	if opts.ConfigFile == "" {
+		opts.ConfigFile = config.FindInDir(".")
+	}
+	if err := validation.ValidateConfigPath(opts.ConfigFile); err != nil {
+		return err
+	}
+
+	input := filesystem.NewMemory()
+	if err := input.WriteFile(stdinFileName, content, 0600); err != nil {
+		return err
+	}
+	// A single input file makes a parse error fail the run instead of being
+	// skipped, so a broken document never yields an empty stream
+	opts.InputPath = stdinFileName
+	opts.FS = input
+	opts.Logger = logger
+	result, err := organize.Organize(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if result.TotalBlocks == 0 {
+		return fmt.Errorf("no Terraform blocks found in stdin")
+	}
+
+	files := filesystem.NewMemory()
+	for _, group := range result.Groups {
+		data, err := input.ReadFile(group.FileName)
+		if err != nil {
+			return err
+		}
+		if err := files.WriteFile(group.FileName, data, 0600); err != nil {
+			return err
+		}
+	}
+	return writeStream(w, format, files)
+}
+
+// writePlannedFiles writes the content of the files created or updated by a
+// dry run to w as a stream.
+func writePlannedFiles(w io.Writer, format string, operations []types.FileOperation) error {
+	files := filesystem.NewMemory()
+	for _, op := range operations {
+		if op.Action != types.ActionCreate && op.Action != types.ActionUpdate {
+			continue
+		}
+		if err := files.MkdirAll(filepath.Dir(op.Path), 0750); err != nil {
+			return err
+		}
+		if err := files.WriteFile(op.Path, op.Content, 0600); err != nil {
+			return err
+		}
+	}
+	return writeStream(w, format, files)
+}
+
+func writeStream(w io.Writer, format string, files *filesystem.Memory) error {
+	switch format {
+	case streamFormatTar:
+		return files.WriteTar(w)
+	default:
+		return files.WriteTxtar(w)
+	}
+}
+
+func validateStreamFormat(format string) error {
+	if format != streamFormatTxtar && format != streamFormatTar {
+		return fmt.Errorf("unknown stream format %q (expected %q or %q)", format, streamFormatTxtar, streamFormatTar)
+	}
+	return nil
+}
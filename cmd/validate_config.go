@@ -1,15 +1,24 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/tomoya-namekawa/tf-file-organize/internal/config"
+	"github.com/tomoya-namekawa/tf-file-organize/internal/splitter"
+	"github.com/tomoya-namekawa/tf-file-organize/internal/usecase"
 	"github.com/tomoya-namekawa/tf-file-organize/internal/validation"
 )
 
+var (
+	validateAgainst   string
+	validateRecursive bool
+)
+
 // validateConfigCmd represents the validate-config command
 var validateConfigCmd = &cobra.Command{
 	Use:   "validate-config <config-file>",
@@ -23,12 +32,19 @@ This command checks:
 - Group name uniqueness
 - Filename conflicts
 - Exclude file pattern validity
+- Patterns shadowed by earlier groups (reported as warnings)
 
-If the configuration is valid, a summary of the configuration will be displayed.`,
+If the configuration is valid, a summary of the configuration will be displayed.
+When the configuration uses 'extends', the fully resolved effective
+configuration is printed as well.
+
+With --against, the Terraform files in the given directory are parsed and a
+coverage report shows how many blocks each group and pattern matched, which
+patterns matched nothing, and which blocks fell through to default files.`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		configFile := args[0]
-		if err := runValidateConfig(configFile); err != nil {
+		if err := runValidateConfig(cmd.Context(), configFile); err != nil {
 			fmt.Fprintf(os.Stderr, "Configuration validation failed: %v\n", err)
 			os.Exit(1)
 		}
@@ -37,9 +53,12 @@ If the configuration is valid, a summary of the configuration will be displayed.
 
 func init() {
 	rootCmd.AddCommand(validateConfigCmd)
+
+	validateConfigCmd.Flags().StringVar(&validateAgainst, "against", "", "Terraform file or directory to report configuration coverage against")
+	validateConfigCmd.Flags().BoolVarP(&validateRecursive, "recursive", "r", false, "Process the --against directory recursively")
 }
 
-func runValidateConfig(configPath string) error {
+func runValidateConfig(ctx context.Context, configPath string) error {
 	// Basic path validation
 	if err := validation.ValidateConfigPath(configPath); err != nil {
 		return err
@@ -60,15 +79,82 @@ func runValidateConfig(configPath string) error {
 
 	// Display configuration summary
 	printConfigSummary(cfg)
+	if err := printEffectiveConfig(cfg); err != nil {
+		return err
+	}
+	printWarnings(cfg)
 
 	fmt.Println("✅ Configuration is valid!")
+
+	if validateAgainst != "" {
+		return runConfigCoverage(ctx, cfg)
+	}
 	return nil
 }
 
+func runConfigCoverage(ctx context.Context, cfg *config.Config) error {
+	if err := validation.ValidateInputPath(validateAgainst); err != nil {
+		return fmt.Errorf("invalid --against path: %w", err)
+	}
+
+	fmt.Println()
+	uc := newUsecase()
+	report, err := uc.ConfigCoverage(ctx, &usecase.ConfigCoverageRequest{
+		InputPath: validateAgainst,
+		Recursive: validateRecursive,
+		Config:    cfg,
+	})
+	if err != nil {
+		return err
+	}
+
+	printCoverageReport(report)
+	return nil
+}
+
+func printCoverageReport(report *splitter.CoverageReport) {
+	fmt.Printf("\n📊 Coverage (%d blocks):\n", report.TotalBlocks)
+
+	var unused []string
+	for _, group := range report.Groups {
+		fmt.Printf("  %s → %s: %d blocks\n", group.Name, group.Filename, group.Blocks)
+		for _, pattern := range group.Patterns {
+			fmt.Printf("     - %s: matched %d, selected %d\n", pattern.Pattern, pattern.Matches, pattern.Selects)
+			if pattern.Matches == 0 {
+				unused = append(unused, fmt.Sprintf("%s (group '%s')", pattern.Pattern, group.Name))
+			}
+		}
+	}
+
+	if len(unused) > 0 {
+		fmt.Println("\n❓ Patterns that matched nothing:")
+		for _, pattern := range unused {
+			fmt.Printf("  - %s\n", pattern)
+		}
+	}
+
+	if len(report.FallThrough) > 0 {
+		fmt.Println("\n↪️  Blocks placed in default files:")
+		for _, block := range report.FallThrough {
+			if block.Excluded {
+				fmt.Printf("  - %s → %s (group file excluded)\n", block.Address, block.FileName)
+			} else {
+				fmt.Printf("  - %s → %s\n", block.Address, block.FileName)
+			}
+		}
+	}
+}
+
 func printConfigSummary(cfg *config.Config) {
 	fmt.Println("\n📋 Configuration Summary:")
 	fmt.Printf("  Groups: %d\n", len(cfg.Groups))
 	fmt.Printf("  Exclude File Patterns: %d\n", len(cfg.ExcludeFiles))
+	if cfg.MatchPrecedence != "" {
+		fmt.Printf("  Match Precedence: %s\n", cfg.MatchPrecedence)
+	}
+	if cfg.MinBlocks > 0 {
+		fmt.Printf("  Min Blocks: %d (smaller groups → %s)\n", cfg.MinBlocks, cfg.CatchAllFilename())
+	}
 
 	if len(cfg.Groups) > 0 {
 		fmt.Println("\n📁 Groups:")
@@ -87,3 +173,42 @@ func printConfigSummary(cfg *config.Config) {
 		}
 	}
 }
+
+func printEffectiveConfig(cfg *config.Config) error {
+	if len(cfg.Extends) == 0 {
+		return nil
+	}
+
+	resolved := *cfg
+	resolved.Extends = nil
+	data, err := config.Marshal(&resolved)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n🧬 Effective Configuration (extends: %s):\n", strings.Join(cfg.Extends, ", "))
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		fmt.Printf("  %s\n", line)
+	}
+	return nil
+}
+
+func printWarnings(cfg *config.Config) {
+	shadowed := config.FindShadowedPatterns(cfg)
+	if len(shadowed) == 0 && len(cfg.Migrations) == 0 {
+		return
+	}
+
+	fmt.Println("\n⚠️  Warnings:")
+	for _, note := range cfg.Migrations {
+		fmt.Printf("  Upgraded older config format: %s (run 'tf-file-organize migrate-config' to update the file)\n", note)
+	}
+	for _, s := range shadowed {
+		scope := "can never match"
+		if len(s.BlockTypes) > 0 {
+			scope = fmt.Sprintf("never matches %s blocks", strings.Join(s.BlockTypes, ", "))
+		}
+		fmt.Printf("  Pattern '%s' in group '%s' %s: shadowed by '%s' in group '%s'\n",
+			s.Pattern, s.Group, scope, s.ShadowingPattern, s.ShadowedBy)
+	}
+}
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tomoya-namekawa/tf-file-organize/internal/validation"
+	"github.com/tomoya-namekawa/tf-file-organize/internal/watch"
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/organize"
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/types"
+)
+
+var (
+	watchDir        string
+	watchConfigFile string
+	watchRecursive  bool
+	watchPerDir     bool
+	watchNormalize  bool
+	watchPlan       bool
+	watchDebounce   time.Duration
+	watchJobs       int
+)
+
+// watchCmd represents the watch command
+var watchCmd = &cobra.Command{
+	Use:   "watch <dir>",
+	Short: "Organize Terraform files whenever they change",
+	Long: `Organize a directory, then keep watching it and organize it again whenever
+its .tf files change, until interrupted.
+
+Bursts of edits, such as an editor saving several files, are handled as one
+change once the directory has been quiet for the --debounce period. Every block
+moved to another file is printed. The files written by the tool itself do not
+trigger another run.
+
+With --plan, the files are never changed: every run only prints the blocks
+that would move.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		watchDir = args[0]
+		if err := runWatch(cmd.Context()); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	watchCmd.Flags().StringVarP(&watchConfigFile, "config", "c", "", "Configuration file for custom grouping rules")
+	watchCmd.Flags().BoolVarP(&watchRecursive, "recursive", "r", false, "Watch and organize subdirectories too")
+	watchCmd.Flags().BoolVar(&watchPerDir, "per-directory", false, "With --recursive, organize each directory with .tf files in place as its own module")
+	watchCmd.Flags().BoolVar(&watchNormalize, "normalize-blocks", false, "Reorder attributes inside blocks into canonical order (meta-arguments first, lifecycle and depends_on last)")
+	watchCmd.Flags().BoolVar(&watchPlan, "plan", false, "Only print what would move, without changing any file")
+	watchCmd.Flags().IntVarP(&watchJobs, "jobs", "j", 0, "Number of files parsed and modules planned at the same time (default: number of CPUs)")
+	watchCmd.Flags().DurationVar(&watchDebounce, "debounce", watch.DefaultDebounce, "How long the directory must be quiet before organizing it")
+}
+
+func runWatch(ctx context.Context) error {
+	if err := validation.ValidateInputPath(watchDir); err != nil {
+		return fmt.Errorf("invalid input path: %w", err)
+	}
+	if err := validation.ValidateConfigPath(watchConfigFile); err != nil {
+		return err
+	}
+	root, err := filepath.Abs(watchDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", watchDir, err)
+	}
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return fmt.Errorf("watch requires a directory: %s", watchDir)
+	}
+
+	w := &watcher{
+		root: root,
+		out:  os.Stdout,
+		opts: organize.Options{
+			InputPath:       root,
+			ConfigFile:      watchConfigFile,
+			Recursive:       watchRecursive,
+			PerDirectory:    watchPerDir,
+			NormalizeBlocks: watchNormalize,
+			DryRun:          watchPlan,
+			Jobs:            watchJobs,
+			Logger:          logger,
+		},
+		writes: watch.NewWrites(),
+	}
+	w.organize(ctx)
+
+	logger.Info("Watching for changes", "path", watchDir)
+	return watch.Watch(ctx, root, watch.Options{Recursive: watchRecursive, Debounce: watchDebounce}, func(paths []string) {
+		paths = slices.DeleteFunc(paths, w.writes.Own)
+		if len(paths) == 0 {
+			logger.Debug("Ignoring changes made by the last run")
+			return
+		}
+		logger.Info("Detected changes", "files", len(paths))
+		w.organize(ctx)
+	})
+}
+
+// watcher runs the organize pipeline for the watch command.
+type watcher struct {
+	root   string
+	out    io.Writer
+	opts   organize.Options
+	writes *watch.Writes // files written by the last run
+}
+
+// organize runs the pipeline once and prints the blocks that moved. Errors
+// are logged rather than returned, so a file saved mid-edit does not stop
+// the watch.
+func (w *watcher) organize(ctx context.Context) {
+	result, err := organize.Organize(ctx, w.opts)
+	if err != nil {
+		logger.Error("Failed to organize files", "error", err)
+		return
+	}
+
+	w.writes.Reset()
+	if !result.DryRun {
+		for _, op := range result.Operations {
+			w.recordWrite(op)
+		}
+	}
+	w.printMoves(result.Groups, result.DryRun)
+}
+
+// recordWrite remembers a file changed by the run, so the change events it
+// causes are ignored.
+func (w *watcher) recordWrite(op types.FileOperation) {
+	path, err := filepath.Abs(op.Path)
+	if err != nil {
+		return
+	}
+	switch op.Action {
+	case types.ActionCreate, types.ActionUpdate:
+		w.writes.Written(path, op.Content)
+	case types.ActionRemove, types.ActionBackup, types.ActionMove:
+		w.writes.Removed(path)
+	}
+}
+
+// printMoves prints every block placed in a file other than the one it was
+// parsed from, with paths relative to the watched directory.
+func (w *watcher) printMoves(groups []*types.BlockGroup, dryRun bool) {
+	verb := "Moved"
+	if dryRun {
+		verb = "Would move"
+	}
+	for _, group := range groups {
+		for _, block := range group.Blocks {
+			target := filepath.Join(filepath.Dir(block.SourceFile), group.FileName)
+			if target == filepath.Clean(block.SourceFile) {
+				continue
+			}
+			fmt.Fprintf(w.out, "%s %s: %s -> %s\n", verb, block.Address(), w.rel(block.SourceFile), w.rel(target))
+		}
+	}
+}
+
+func (w *watcher) rel(path string) string {
+	if rel, err := filepath.Rel(w.root, path); err == nil {
+		return rel
+	}
+	return path
+}
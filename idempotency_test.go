@@ -108,14 +108,14 @@ output "instance_id" {
 		logContentDifferences(t, contents1, contents2, contents3)
 	}
 
-	// Check that second and third runs don't show "Created file" messages
+	// Check that second and third runs don't show "Created file" or "Updated file" messages
 	// (indicating proper idempotency)
-	if strings.Contains(string(output2), "Created file:") {
-		t.Errorf("Second run shows 'Created file' messages, indicating lack of idempotency:\n%s", output2)
+	if strings.Contains(string(output2), "Created file ") || strings.Contains(string(output2), "Updated file ") {
+		t.Errorf("Second run shows 'Created file' or 'Updated file' messages, indicating lack of idempotency:\n%s", output2)
 	}
 
-	if strings.Contains(string(output3), "Created file:") {
-		t.Errorf("Third run shows 'Created file' messages, indicating lack of idempotency:\n%s", output3)
+	if strings.Contains(string(output3), "Created file ") || strings.Contains(string(output3), "Updated file ") {
+		t.Errorf("Third run shows 'Created file' or 'Updated file' messages, indicating lack of idempotency:\n%s", output3)
 	}
 }
 
@@ -221,9 +221,9 @@ exclude_files:
 			logContentDifferences(t, allContents[0], allContents[i], nil)
 		}
 
-		// Check that subsequent runs don't show "Created file" messages
-		if strings.Contains(string(allOutputs[i]), "Created file:") {
-			t.Errorf("Run %d shows 'Created file' messages, indicating lack of idempotency:\n%s", i+1, allOutputs[i])
+		// Check that subsequent runs don't show "Created file" or "Updated file" messages
+		if strings.Contains(string(allOutputs[i]), "Created file ") || strings.Contains(string(allOutputs[i]), "Updated file ") {
+			t.Errorf("Run %d shows 'Created file' or 'Updated file' messages, indicating lack of idempotency:\n%s", i+1, allOutputs[i])
 		}
 	}
 }
@@ -326,8 +326,8 @@ groups:
 
 	// Check that only the first run shows "Created file" messages
 	for i := 1; i < len(outputs); i++ {
-		if strings.Contains(string(outputs[i]), "Created file:") {
-			t.Errorf("Run %d shows 'Created file' messages, indicating lack of idempotency:\n%s", i+1, outputs[i])
+		if strings.Contains(string(outputs[i]), "Created file ") || strings.Contains(string(outputs[i]), "Updated file ") {
+			t.Errorf("Run %d shows 'Created file' or 'Updated file' messages, indicating lack of idempotency:\n%s", i+1, outputs[i])
 		}
 	}
 
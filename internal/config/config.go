@@ -11,9 +11,31 @@ import (
 	"github.com/goccy/go-yaml"
 )
 
+// DefaultCatchAllFile is the file that receives groups folded by MinBlocks
+// when CatchAllFile is not set.
+const DefaultCatchAllFile = "main.tf"
+
+// Match precedence modes for resolving a block against group patterns.
+const (
+	// PrecedenceFirst picks the first group in config order whose pattern matches.
+	PrecedenceFirst = "first"
+	// PrecedenceSpecific picks the most specific matching pattern across all groups.
+	PrecedenceSpecific = "specific"
+)
+
 type Config struct {
-	Groups       []GroupConfig `yaml:"groups"`
-	ExcludeFiles []string      `yaml:"exclude_files"`
+	Version         int           `yaml:"version,omitempty"`
+	Groups          []GroupConfig `yaml:"groups"`
+	ExcludeFiles    []string      `yaml:"exclude_files,omitempty"`
+	MinBlocks       int           `yaml:"min_blocks,omitempty"`
+	CatchAllFile    string        `yaml:"catch_all_file,omitempty"`
+	MatchPrecedence string        `yaml:"match_precedence,omitempty"`
+	Root            bool          `yaml:"root,omitempty"` // stop inheriting configs from parent directories
+	Extends         []string      `yaml:"extends,omitempty"`
+	Output          *OutputConfig `yaml:"output,omitempty"`
+
+	// Migrations notes upgrades applied in memory to older config files.
+	Migrations []string `yaml:"-"`
 }
 
 type GroupConfig struct {
@@ -27,6 +49,12 @@ func LoadConfig(configPath string) (*Config, error) {
 		return &Config{}, nil
 	}
 
+	return loadConfigFile(configPath, nil)
+}
+
+// loadConfigFile loads a config file and resolves its extends chain. chain holds
+// the files currently being resolved and is used to detect cycles.
+func loadConfigFile(configPath string, chain []string) (*Config, error) {
 	if !filepath.IsAbs(configPath) {
 		abs, err := filepath.Abs(configPath)
 		if err != nil {
@@ -35,6 +63,10 @@ func LoadConfig(configPath string) (*Config, error) {
 		configPath = abs
 	}
 
+	if slices.Contains(chain, configPath) {
+		return nil, fmt.Errorf("circular extends: %s", strings.Join(append(chain, configPath), " -> "))
+	}
+
 	stat, err := os.Stat(configPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to access config file: %w", err)
@@ -54,6 +86,32 @@ func LoadConfig(configPath string) (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
+	config, err := parseConfigData(data)
+	if err != nil {
+		return nil, err
+	}
+	for i, note := range config.Migrations {
+		config.Migrations[i] = fmt.Sprintf("%s: %s", configPath, note)
+	}
+
+	config, err = resolveExtends(config, filepath.Dir(configPath), append(chain, configPath))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateConfig(config); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return config, nil
+}
+
+func parseConfigData(data []byte) (*Config, error) {
+	data, migrations, err := migrateData(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
 	if err := validateConfigFields(data); err != nil {
 		return nil, fmt.Errorf("invalid configuration fields: %w", err)
 	}
@@ -62,14 +120,20 @@ func LoadConfig(configPath string) (*Config, error) {
 	if err := yaml.Unmarshal(data, &config); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
-
-	if err := validateConfig(&config); err != nil {
-		return nil, fmt.Errorf("invalid configuration: %w", err)
-	}
+	config.Migrations = migrations
 
 	return &config, nil
 }
 
+// Marshal serializes a configuration to YAML.
+func Marshal(cfg *Config) ([]byte, error) {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return data, nil
+}
+
 func ValidateConfig(cfg *Config) error {
 	groupNames := make(map[string]bool)
 	for i, group := range cfg.Groups {
@@ -97,6 +161,13 @@ func ValidateConfig(cfg *Config) error {
 		}
 	}
 
+	if cfg.MinBlocks > 0 {
+		catchAll := cfg.CatchAllFilename()
+		if existingGroup, exists := filenames[catchAll]; exists {
+			return fmt.Errorf("catch_all_file '%s' is already used by group '%s'", catchAll, existingGroup)
+		}
+	}
+
 	// Validate exclude file patterns
 	for i, pattern := range cfg.ExcludeFiles {
 		if pattern == "" {
@@ -127,9 +198,54 @@ func validateConfig(config *Config) error {
 	if err := validateExcludeFilePatterns(config.ExcludeFiles); err != nil {
 		return err
 	}
+	if err := validateCatchAll(config); err != nil {
+		return err
+	}
+	switch config.MatchPrecedence {
+	case "", PrecedenceFirst, PrecedenceSpecific:
+	default:
+		return fmt.Errorf("match_precedence must be '%s' or '%s': %s", PrecedenceFirst, PrecedenceSpecific, config.MatchPrecedence)
+	}
+	return validateOutput(config.Output)
+}
+
+func validateCatchAll(config *Config) error {
+	if config.MinBlocks < 0 {
+		return fmt.Errorf("min_blocks cannot be negative: %d", config.MinBlocks)
+	}
+	if config.CatchAllFile != "" {
+		if err := validateFilename(config.CatchAllFile); err != nil {
+			return fmt.Errorf("catch_all_file: invalid filename: %w", err)
+		}
+		if isDefaultFilename(config.CatchAllFile) {
+			return fmt.Errorf("catch_all_file '%s' is already used for blocks that are not grouped", config.CatchAllFile)
+		}
+	}
 	return nil
 }
 
+// defaultFilenames are the files blocks go to when no group matches them.
+var defaultFilenames = []string{
+	"variables.tf", "outputs.tf", "providers.tf", "locals.tf", "terraform.tf",
+	"resource.tf", "data.tf", "module.tf", "moved.tf", "import.tf", "removed.tf", "check.tf",
+}
+
+// defaultFilenamePrefixes start the files named after a block's type or name,
+// such as resource__aws_instance.tf.
+var defaultFilenamePrefixes = []string{"resource__", "data__", "module__", "output__", "variable__"}
+
+func isDefaultFilename(filename string) bool {
+	if slices.Contains(defaultFilenames, filename) {
+		return true
+	}
+	for _, prefix := range defaultFilenamePrefixes {
+		if strings.HasPrefix(filename, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 func validateGroups(groups []GroupConfig) error {
 	for i, group := range groups {
 		if group.Name == "" {
@@ -214,14 +330,240 @@ func validateFilename(filename string) error {
 }
 
 func (c *Config) FindGroupForResource(resourceType string) *GroupConfig {
-	for _, group := range c.Groups {
+	group, _, _ := c.FindGroupForCandidates([]string{resourceType})
+	return group
+}
+
+// FindGroupForCandidates resolves the group for a block given its match candidates
+// in priority order. It returns the winning group along with the candidate and
+// pattern that selected it, or nil if no pattern matches.
+func (c *Config) FindGroupForCandidates(candidates []string) (group *GroupConfig, candidate, pattern string) {
+	if c.MatchPrecedence == PrecedenceSpecific {
+		return c.findMostSpecific(candidates)
+	}
+
+	for _, candidate := range candidates {
+		for i := range c.Groups {
+			for _, pattern := range c.Groups[i].Patterns {
+				if c.matchPattern(pattern, candidate) {
+					return &c.Groups[i], candidate, pattern
+				}
+			}
+		}
+	}
+	return nil, "", ""
+}
+
+// findMostSpecific picks the matching pattern with the highest specificity.
+// Ties go to the earlier candidate, then to the earlier group in config order.
+func (c *Config) findMostSpecific(candidates []string) (group *GroupConfig, candidate, pattern string) {
+	var best patternSpecificity
+	for _, cand := range candidates {
+		for i := range c.Groups {
+			for _, p := range c.Groups[i].Patterns {
+				if !c.matchPattern(p, cand) {
+					continue
+				}
+				if spec := specificity(p); group == nil || spec.moreSpecificThan(best) {
+					group, candidate, pattern, best = &c.Groups[i], cand, p, spec
+				}
+			}
+		}
+	}
+	return group, candidate, pattern
+}
+
+// patternSpecificity orders patterns: exact patterns first, then patterns with
+// more dot-separated segments, then patterns with more literal characters.
+type patternSpecificity struct {
+	exact    bool
+	segments int
+	literals int
+}
+
+func specificity(pattern string) patternSpecificity {
+	return patternSpecificity{
+		exact:    !strings.Contains(pattern, "*"),
+		segments: strings.Count(pattern, ".") + 1,
+		literals: len(pattern) - strings.Count(pattern, "*"),
+	}
+}
+
+func (p patternSpecificity) moreSpecificThan(other patternSpecificity) bool {
+	if p.exact != other.exact {
+		return p.exact
+	}
+	if p.segments != other.segments {
+		return p.segments > other.segments
+	}
+	return p.literals > other.literals
+}
+
+// ShadowedPattern describes a group pattern that can never win because
+// another group takes the blocks it matches first.
+type ShadowedPattern struct {
+	Group            string
+	Pattern          string
+	ShadowedBy       string
+	ShadowingPattern string
+	BlockTypes       []string // block types the pattern never wins for; nil if it never wins at all
+}
+
+// blockCandidateLabels lists the block types and how many of their labels
+// make up match candidates: sub type and name, sub type only, or none.
+var blockCandidateLabels = []struct {
+	blockType string
+	labels    int
+}{
+	{"resource", 2}, {"data", 2},
+	{"module", 1}, {"provider", 1}, {"variable", 1}, {"output", 1},
+	{"locals", 0}, {"terraform", 0}, {"moved", 0}, {"import", 0}, {"removed", 0}, {"check", 0},
+}
+
+// FindShadowedPatterns reports patterns that can never match under the
+// configured precedence because another group shadows them.
+func FindShadowedPatterns(cfg *Config) []ShadowedPattern {
+	var shadowed []ShadowedPattern
+	for j, group := range cfg.Groups {
 		for _, pattern := range group.Patterns {
-			if c.matchPattern(pattern, resourceType) {
-				return &group
+			if s, ok := cfg.findShadow(j, pattern); ok {
+				shadowed = append(shadowed, s)
 			}
 		}
 	}
-	return nil
+	return shadowed
+}
+
+// findShadow resolves, for each block type, the candidates of the most general
+// blocks the pattern matches, in the order FindGroupForCandidates uses. Each
+// '*' of the pattern is kept as literal text standing for any name, so a
+// pattern that matches such a candidate matches every block it stands for.
+// The pattern is shadowed for a block type when another group wins for all of
+// its blocks.
+func (c *Config) findShadow(groupIndex int, pattern string) (ShadowedPattern, bool) {
+	result := ShadowedPattern{Group: c.Groups[groupIndex].Name, Pattern: pattern}
+	matched := 0
+	for _, bt := range blockCandidateLabels {
+		samples := c.sampleCandidates(pattern, bt.blockType, bt.labels)
+		if len(samples) == 0 {
+			continue
+		}
+		matched++
+
+		var winner *GroupConfig
+		var winnerPattern string
+		for _, candidates := range samples {
+			group, _, p := c.FindGroupForCandidates(candidates)
+			if group == nil || group == &c.Groups[groupIndex] {
+				winner = nil
+				break
+			}
+			if winner == nil {
+				winner, winnerPattern = group, p
+			}
+		}
+		if winner == nil {
+			continue
+		}
+		if result.ShadowedBy == "" {
+			result.ShadowedBy, result.ShadowingPattern = winner.Name, winnerPattern
+		}
+		result.BlockTypes = append(result.BlockTypes, bt.blockType)
+	}
+
+	if len(result.BlockTypes) == 0 {
+		return ShadowedPattern{}, false
+	}
+	if len(result.BlockTypes) == matched {
+		result.BlockTypes = nil
+	}
+	return result, true
+}
+
+// sampleCandidates returns the match candidates of the most general blocks of
+// blockType that pattern matches, one list per candidate level it can match.
+func (c *Config) sampleCandidates(pattern, blockType string, labels int) [][]string {
+	candidates := func(subType, name string) []string {
+		var list []string
+		if subType != "" && name != "" {
+			list = append(list, blockType+"."+subType+"."+name)
+		}
+		if subType != "" {
+			list = append(list, blockType+"."+subType, subType)
+		}
+		return append(list, blockType)
+	}
+	anyName := ""
+	if labels == 2 {
+		anyName = "*"
+	}
+
+	var samples [][]string
+	if labels == 2 {
+		for _, text := range expandSegments(pattern, 3) {
+			segments := strings.Split(text, ".")
+			if c.matchPattern(segments[0], blockType) {
+				samples = append(samples, candidates(segments[1], segments[2]))
+			}
+		}
+	}
+	if labels > 0 {
+		for _, text := range expandSegments(pattern, 2) {
+			segments := strings.Split(text, ".")
+			if c.matchPattern(segments[0], blockType) {
+				samples = append(samples, candidates(segments[1], anyName))
+			}
+		}
+		if !strings.Contains(pattern, ".") {
+			samples = append(samples, candidates(pattern, anyName))
+		}
+	}
+	if c.matchPattern(pattern, blockType) {
+		if labels > 0 {
+			samples = append(samples, candidates("*", anyName))
+		} else {
+			samples = append(samples, candidates("", ""))
+		}
+	}
+	return samples
+}
+
+// expandSegments returns the texts with exactly n dot-separated segments that
+// pattern matches when its '*' wildcards span dots, keeping each '*' in place.
+func expandSegments(pattern string, n int) []string {
+	var texts []string
+	var expand func(rest string, text string, dots int)
+	expand = func(rest string, text string, dots int) {
+		if dots > n-1 {
+			return
+		}
+		if rest == "" {
+			if dots == n-1 {
+				texts = append(texts, text)
+			}
+			return
+		}
+		if rest[0] != '*' {
+			if rest[0] == '.' {
+				dots++
+			}
+			expand(rest[1:], text+rest[:1], dots)
+			return
+		}
+		for extra := 0; extra <= n-1-dots; extra++ {
+			expand(rest[1:], text+"*"+strings.Repeat(".*", extra), dots+extra)
+		}
+	}
+	expand(pattern, "", 0)
+	return texts
+}
+
+// CatchAllFilename returns the file that receives groups smaller than MinBlocks.
+func (c *Config) CatchAllFilename() string {
+	if c.CatchAllFile != "" {
+		return c.CatchAllFile
+	}
+	return DefaultCatchAllFile
 }
 
 func (c *Config) IsFileExcluded(filename string) bool {
@@ -233,6 +575,11 @@ func (c *Config) IsFileExcluded(filename string) bool {
 	return false
 }
 
+// MatchPattern reports whether text matches a group or exclude pattern.
+func (c *Config) MatchPattern(pattern, text string) bool {
+	return c.matchPattern(pattern, text)
+}
+
 func (c *Config) matchPattern(pattern, text string) bool {
 	if strings.Contains(pattern, "*") {
 		return c.wildcardMatch(pattern, text)
@@ -280,61 +627,40 @@ func (c *Config) matchWithWildcards(pattern, text string) bool {
 	return patternIndex == len(pattern)
 }
 
+// validateConfigFields reports deprecated keys with a hint on how to replace
+// them, then checks the document against the config schema.
 func validateConfigFields(data []byte) error {
-	var rawConfig map[string]any
+	var rawConfig any
 	if err := yaml.Unmarshal(data, &rawConfig); err != nil {
 		return fmt.Errorf("failed to parse YAML: %w", err)
 	}
 
-	validTopLevelFields := map[string]bool{
-		"groups":        true,
-		"exclude_files": true,
-	}
-
-	var invalidFields []string
-	var deprecatedFields []string
+	var errorMessages []string
 
-	for field := range rawConfig {
-		if !validTopLevelFields[field] {
+	if fields, ok := rawConfig.(map[string]any); ok {
+		var deprecatedFields []string
+		for _, field := range []string{"exclude", "overrides"} {
+			if _, exists := fields[field]; !exists {
+				continue
+			}
 			switch field {
 			case "exclude":
 				deprecatedFields = append(deprecatedFields, fmt.Sprintf("'%s' (use 'exclude_files' instead)", field))
 			case "overrides":
 				deprecatedFields = append(deprecatedFields, fmt.Sprintf("'%s' (no longer supported)", field))
-			default:
-				invalidFields = append(invalidFields, fmt.Sprintf("'%s'", field))
 			}
+			delete(fields, field)
 		}
-	}
-
-	if groupsInterface, exists := rawConfig["groups"]; exists {
-		if groups, ok := groupsInterface.([]any); ok {
-			validGroupFields := map[string]bool{
-				"name":     true,
-				"filename": true,
-				"patterns": true,
-			}
-
-			for i, groupInterface := range groups {
-				if group, ok := groupInterface.(map[string]any); ok {
-					for field := range group {
-						if !validGroupFields[field] {
-							invalidFields = append(invalidFields, fmt.Sprintf("'%s' in group %d", field, i+1))
-						}
-					}
-				}
-			}
+		if len(deprecatedFields) > 0 {
+			errorMessages = append(errorMessages, fmt.Sprintf("deprecated fields found: %s", strings.Join(deprecatedFields, ", ")))
 		}
 	}
 
-	var errorMessages []string
-
-	if len(deprecatedFields) > 0 {
-		errorMessages = append(errorMessages, fmt.Sprintf("deprecated fields found: %s", strings.Join(deprecatedFields, ", ")))
-	}
-
-	if len(invalidFields) > 0 {
-		errorMessages = append(errorMessages, fmt.Sprintf("unknown fields found: %s", strings.Join(invalidFields, ", ")))
+	// An empty document decodes to nil and is a valid empty config
+	if rawConfig != nil {
+		for _, schemaErr := range validateSchema(rawConfig) {
+			errorMessages = append(errorMessages, schemaErr.Error())
+		}
 	}
 
 	if len(errorMessages) > 0 {
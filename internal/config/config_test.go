@@ -3,9 +3,12 @@ package config_test
 import (
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/tomoya-namekawa/tf-file-organize/internal/config"
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/types"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -200,6 +203,7 @@ exclude_files:
 		{
 			name: "deprecated exclude field",
 			configYAML: `
+version: 2
 groups:
   - name: "compute"
     filename: "compute.tf"
@@ -222,7 +226,7 @@ groups:
 unknown_field: "value"
 `,
 			expectError:   true,
-			errorContains: "unknown fields found: 'unknown_field'",
+			errorContains: "unknown_field: unknown field",
 		},
 		{
 			name: "invalid group field",
@@ -237,11 +241,12 @@ exclude_files:
   - "*.backup"
 `,
 			expectError:   true,
-			errorContains: "unknown fields found: 'priority' in group 1",
+			errorContains: "groups[0].priority: unknown field",
 		},
 		{
 			name: "multiple invalid fields",
 			configYAML: `
+version: 2
 groups:
   - name: "compute"
     filename: "compute.tf"
@@ -259,6 +264,7 @@ unknown_top: "value"
 		{
 			name: "deprecated overrides field",
 			configYAML: `
+version: 2
 groups:
   - name: "compute"
     filename: "compute.tf"
@@ -299,6 +305,197 @@ overrides:
 	}
 }
 
+func TestCatchAllConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "test-config.yaml")
+
+	configContent := `
+min_blocks: 3
+catch_all_file: "misc.tf"
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.MinBlocks != 3 {
+		t.Errorf("Expected min_blocks 3, got %d", cfg.MinBlocks)
+	}
+	if cfg.CatchAllFilename() != "misc.tf" {
+		t.Errorf("Expected catch-all file 'misc.tf', got '%s'", cfg.CatchAllFilename())
+	}
+
+	if (&config.Config{}).CatchAllFilename() != config.DefaultCatchAllFile {
+		t.Errorf("Expected default catch-all file '%s'", config.DefaultCatchAllFile)
+	}
+
+	conflicting := &config.Config{
+		Groups:    []config.GroupConfig{{Name: "main", Filename: "main.tf", Patterns: []string{"aws_*"}}},
+		MinBlocks: 2,
+	}
+	if err := config.ValidateConfig(conflicting); err == nil {
+		t.Error("Expected error for catch_all_file conflicting with a group filename")
+	}
+
+	for _, filename := range []string{"variables.tf", "outputs.tf", "providers.tf", "locals.tf", "terraform.tf", "resource__aws_instance.tf", "module__vpc.tf"} {
+		if err := os.WriteFile(configPath, []byte("min_blocks: 2\ncatch_all_file: \""+filename+"\"\n"), 0644); err != nil {
+			t.Fatalf("Failed to create test config file: %v", err)
+		}
+		if _, err := config.LoadConfig(configPath); err == nil || !strings.Contains(err.Error(), "catch_all_file") {
+			t.Errorf("Expected error for catch_all_file '%s' conflicting with a default filename, got %v", filename, err)
+		}
+	}
+}
+
+func TestMatchPrecedence(t *testing.T) {
+	groups := []config.GroupConfig{
+		{
+			Name:     "aws",
+			Filename: "aws.tf",
+			Patterns: []string{"aws_*"},
+		},
+		{
+			Name:     "iam",
+			Filename: "iam.tf",
+			Patterns: []string{"aws_iam_*"},
+		},
+		{
+			Name:     "web",
+			Filename: "web.tf",
+			Patterns: []string{"resource.aws_instance.web*"},
+		},
+		{
+			Name:     "compute",
+			Filename: "compute.tf",
+			Patterns: []string{"aws_instance"},
+		},
+	}
+
+	testCases := []struct {
+		precedence string
+		candidates []string
+		expected   string
+	}{
+		{config.PrecedenceFirst, []string{"aws_iam_role"}, "aws"},
+		{config.PrecedenceSpecific, []string{"aws_iam_role"}, "iam"},
+		{config.PrecedenceFirst, []string{"resource.aws_instance.api", "resource.aws_instance", "aws_instance"}, "aws"},
+		{config.PrecedenceSpecific, []string{"resource.aws_instance.api", "resource.aws_instance", "aws_instance"}, "compute"},
+		{config.PrecedenceSpecific, []string{"resource.aws_instance.web1", "resource.aws_instance", "aws_instance"}, "compute"},
+		{config.PrecedenceSpecific, []string{"aws_s3_bucket"}, "aws"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.precedence+"_"+tc.candidates[0], func(t *testing.T) {
+			cfg := &config.Config{Groups: groups, MatchPrecedence: tc.precedence}
+			group, _, _ := cfg.FindGroupForCandidates(tc.candidates)
+			if group == nil {
+				t.Fatalf("Expected group %s, got nil", tc.expected)
+			}
+			if group.Name != tc.expected {
+				t.Errorf("Expected group %s, got %s", tc.expected, group.Name)
+			}
+		})
+	}
+}
+
+func TestFindShadowedPatterns(t *testing.T) {
+	groups := []config.GroupConfig{
+		{Name: "aws", Filename: "aws.tf", Patterns: []string{"aws_*"}},
+		{Name: "iam", Filename: "iam.tf", Patterns: []string{"aws_iam_*", "google_*"}},
+		{Name: "web", Filename: "web.tf", Patterns: []string{"resource.aws_instance.web"}},
+	}
+
+	shadowed := config.FindShadowedPatterns(&config.Config{Groups: groups})
+	if len(shadowed) != 1 {
+		t.Fatalf("Expected 1 shadowed pattern, got %d: %+v", len(shadowed), shadowed)
+	}
+	if shadowed[0].Pattern != "aws_iam_*" || shadowed[0].ShadowedBy != "aws" {
+		t.Errorf("Unexpected shadowed pattern: %+v", shadowed[0])
+	}
+
+	shadowed = config.FindShadowedPatterns(&config.Config{Groups: groups, MatchPrecedence: config.PrecedenceSpecific})
+	if len(shadowed) != 0 {
+		t.Errorf("Expected no shadowed patterns with specific precedence, got %+v", shadowed)
+	}
+}
+
+func TestFindShadowedPatternsAcrossCandidateLevels(t *testing.T) {
+	tests := []struct {
+		name       string
+		groups     []config.GroupConfig
+		precedence string
+		expected   []config.ShadowedPattern
+	}{
+		{
+			name: "type pattern shadows sub type pattern for resources",
+			groups: []config.GroupConfig{
+				{Name: "all", Filename: "all.tf", Patterns: []string{"resource.*"}},
+				{Name: "compute", Filename: "compute.tf", Patterns: []string{"aws_instance"}},
+			},
+			expected: []config.ShadowedPattern{
+				{Group: "compute", Pattern: "aws_instance", ShadowedBy: "all", ShadowingPattern: "resource.*", BlockTypes: []string{"resource"}},
+			},
+		},
+		{
+			name: "later group shadows through a higher priority candidate",
+			groups: []config.GroupConfig{
+				{Name: "compute", Filename: "compute.tf", Patterns: []string{"aws_instance"}},
+				{Name: "all", Filename: "all.tf", Patterns: []string{"resource.*"}},
+			},
+			expected: []config.ShadowedPattern{
+				{Group: "compute", Pattern: "aws_instance", ShadowedBy: "all", ShadowingPattern: "resource.*", BlockTypes: []string{"resource"}},
+			},
+		},
+		{
+			name: "block type pattern shadowed by sub type candidates",
+			groups: []config.GroupConfig{
+				{Name: "meta", Filename: "meta.tf", Patterns: []string{"variable"}},
+				{Name: "vars", Filename: "vars.tf", Patterns: []string{"variable.*"}},
+			},
+			expected: []config.ShadowedPattern{
+				{Group: "meta", Pattern: "variable", ShadowedBy: "vars", ShadowingPattern: "variable.*", BlockTypes: []string{"variable"}},
+			},
+		},
+		{
+			name: "data pattern shadows data sources only",
+			groups: []config.GroupConfig{
+				{Name: "data", Filename: "data.tf", Patterns: []string{"data.*"}},
+				{Name: "images", Filename: "images.tf", Patterns: []string{"aws_ami"}},
+			},
+			expected: []config.ShadowedPattern{
+				{Group: "images", Pattern: "aws_ami", ShadowedBy: "data", ShadowingPattern: "data.*", BlockTypes: []string{"data"}},
+			},
+		},
+		{
+			name: "name pattern does not shadow sub type pattern",
+			groups: []config.GroupConfig{
+				{Name: "web", Filename: "web.tf", Patterns: []string{"resource.aws_instance.web*"}},
+				{Name: "compute", Filename: "compute.tf", Patterns: []string{"aws_instance"}},
+			},
+		},
+		{
+			name: "specific precedence prefers the exact pattern",
+			groups: []config.GroupConfig{
+				{Name: "all", Filename: "all.tf", Patterns: []string{"resource.*"}},
+				{Name: "compute", Filename: "compute.tf", Patterns: []string{"aws_instance"}},
+			},
+			precedence: config.PrecedenceSpecific,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shadowed := config.FindShadowedPatterns(&config.Config{Groups: tt.groups, MatchPrecedence: tt.precedence})
+			if !reflect.DeepEqual(shadowed, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, shadowed)
+			}
+		})
+	}
+}
+
 func containsString(haystack, needle string) bool {
 	if len(needle) == 0 {
 		return true
@@ -310,3 +507,40 @@ func containsString(haystack, needle string) bool {
 	}
 	return false
 }
+
+func TestOutputConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "test-config.yaml")
+
+	configContent := `
+output:
+  line_ending: crlf
+  bom: false
+  file_mode: "0644"
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	detected := types.FileFormat{BOM: true, Mode: 0600}
+	want := types.FileFormat{CRLF: true, Mode: 0644}
+	if got := cfg.Output.Apply(detected); got != want {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+
+	// Without overrides the detected format is kept
+	if got := (&config.Config{}).Output.Apply(detected); got != detected {
+		t.Errorf("Expected %+v, got %+v", detected, got)
+	}
+
+	if err := os.WriteFile(configPath, []byte("output:\n  file_mode: \"0999\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test config file: %v", err)
+	}
+	if _, err := config.LoadConfig(configPath); err == nil {
+		t.Error("Expected error for invalid file_mode")
+	}
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// DefaultFileNames lists the configuration file names searched for in each
+// directory, in priority order.
+var DefaultFileNames = []string{
+	"tf-file-organize.yaml",
+	"tf-file-organize.yml",
+	".tf-file-organize.yaml",
+	".tf-file-organize.yml",
+}
+
+// FindInDir returns the first default configuration file present in dir, or "".
+func FindInDir(dir string) string {
+	for _, name := range DefaultFileNames {
+		path := filepath.Join(dir, name)
+		if stat, err := os.Stat(path); err == nil && stat.Mode().IsRegular() {
+			return path
+		}
+	}
+	return ""
+}
+
+// LoadDiscovered walks up from startDir to the repository root (the first
+// directory containing .git) or the filesystem root, loading the configuration
+// files found on the way. Nearer configs are merged on top of farther ones, and
+// the walk stops at a config marked root: true. It returns the effective config
+// and the files it was built from, nearest first.
+func LoadDiscovered(startDir string) (*Config, []string, error) {
+	dir, err := filepath.Abs(startDir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	var files []string
+	var configs []*Config
+	for {
+		if path := FindInDir(dir); path != "" {
+			cfg, err := LoadConfig(path)
+			if err != nil {
+				return nil, nil, err
+			}
+			files = append(files, path)
+			configs = append(configs, cfg)
+			if cfg.Root {
+				break
+			}
+		}
+
+		if isRepositoryRoot(dir) {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	if len(configs) == 0 {
+		return &Config{}, nil, nil
+	}
+
+	merged := configs[len(configs)-1]
+	for i := len(configs) - 2; i >= 0; i-- {
+		merged = Merge(merged, configs[i])
+	}
+	if err := ValidateConfig(merged); err != nil {
+		return nil, nil, fmt.Errorf("invalid merged configuration for %s: %w", startDir, err)
+	}
+
+	return merged, files, nil
+}
+
+func isRepositoryRoot(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, ".git"))
+	return err == nil
+}
+
+// Merge returns child layered on top of parent. Child groups come first so they
+// win under first-match precedence, a child group replaces a parent group with
+// the same name or filename, and patterns claimed by the child are removed from
+// inherited groups. Exclude patterns are combined, and scalar settings set in the
+// child override the parent's.
+func Merge(parent, child *Config) *Config {
+	if parent == nil {
+		return child
+	}
+
+	merged := &Config{
+		MinBlocks:       parent.MinBlocks,
+		CatchAllFile:    parent.CatchAllFile,
+		MatchPrecedence: parent.MatchPrecedence,
+		Root:            child.Root,
+		Version:         child.Version,
+		Migrations:      append(append([]string{}, parent.Migrations...), child.Migrations...),
+	}
+
+	childNames := make(map[string]bool)
+	childFiles := make(map[string]bool)
+	childPatterns := make(map[string]bool)
+	for _, group := range child.Groups {
+		childNames[group.Name] = true
+		childFiles[group.Filename] = true
+		for _, pattern := range group.Patterns {
+			childPatterns[pattern] = true
+		}
+		merged.Groups = append(merged.Groups, group)
+	}
+	for _, group := range parent.Groups {
+		if childNames[group.Name] || childFiles[group.Filename] {
+			continue
+		}
+		// Patterns claimed by the child are dropped from inherited groups
+		var patterns []string
+		for _, pattern := range group.Patterns {
+			if !childPatterns[pattern] {
+				patterns = append(patterns, pattern)
+			}
+		}
+		if len(patterns) == 0 {
+			continue
+		}
+		group.Patterns = patterns
+		merged.Groups = append(merged.Groups, group)
+	}
+
+	seen := make(map[string]bool)
+	for _, pattern := range append(append([]string{}, parent.ExcludeFiles...), child.ExcludeFiles...) {
+		if !seen[pattern] {
+			seen[pattern] = true
+			merged.ExcludeFiles = append(merged.ExcludeFiles, pattern)
+		}
+	}
+
+	if child.MinBlocks != 0 {
+		merged.MinBlocks = child.MinBlocks
+	}
+	if child.CatchAllFile != "" {
+		merged.CatchAllFile = child.CatchAllFile
+	}
+	if child.MatchPrecedence != "" {
+		merged.MatchPrecedence = child.MatchPrecedence
+	}
+	merged.Output = mergeOutput(parent.Output, child.Output)
+
+	return merged
+}
@@ -0,0 +1,138 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tomoya-namekawa/tf-file-organize/internal/config"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func TestLoadDiscovered(t *testing.T) {
+	repo := t.TempDir()
+	if err := os.Mkdir(filepath.Join(repo, ".git"), 0755); err != nil {
+		t.Fatalf("Failed to create .git: %v", err)
+	}
+
+	writeFile(t, filepath.Join(repo, "tf-file-organize.yaml"), `
+groups:
+  - name: "network"
+    filename: "network.tf"
+    patterns:
+      - "aws_vpc"
+exclude_files:
+  - "keep-*.tf"
+`)
+	writeFile(t, filepath.Join(repo, "stacks", "tf-file-organize.yml"), `
+groups:
+  - name: "compute"
+    filename: "compute.tf"
+    patterns:
+      - "aws_instance"
+exclude_files:
+  - "local-*.tf"
+`)
+	prod := filepath.Join(repo, "stacks", "prod")
+	if err := os.MkdirAll(prod, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	cfg, files, err := config.LoadDiscovered(prod)
+	if err != nil {
+		t.Fatalf("LoadDiscovered failed: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 config files, got %v", files)
+	}
+	if len(cfg.Groups) != 2 || cfg.Groups[0].Name != "compute" || cfg.Groups[1].Name != "network" {
+		t.Errorf("Expected nearer groups first, got %+v", cfg.Groups)
+	}
+	if len(cfg.ExcludeFiles) != 2 {
+		t.Errorf("Expected exclude patterns to be combined, got %v", cfg.ExcludeFiles)
+	}
+
+	writeFile(t, filepath.Join(prod, ".tf-file-organize.yaml"), `
+root: true
+groups:
+  - name: "all"
+    filename: "all.tf"
+    patterns:
+      - "*"
+`)
+	cfg, files, err = config.LoadDiscovered(prod)
+	if err != nil {
+		t.Fatalf("LoadDiscovered failed: %v", err)
+	}
+	if len(files) != 1 || len(cfg.Groups) != 1 || cfg.Groups[0].Name != "all" {
+		t.Errorf("Expected root config to stop inheritance, got files %v groups %+v", files, cfg.Groups)
+	}
+}
+
+func TestLoadDiscoveredStopsAtRepositoryRoot(t *testing.T) {
+	outer := t.TempDir()
+	writeFile(t, filepath.Join(outer, "tf-file-organize.yaml"), `
+groups:
+  - name: "outside"
+    filename: "outside.tf"
+    patterns:
+      - "aws_vpc"
+`)
+	repo := filepath.Join(outer, "repo")
+	if err := os.MkdirAll(filepath.Join(repo, ".git"), 0755); err != nil {
+		t.Fatalf("Failed to create .git: %v", err)
+	}
+
+	cfg, files, err := config.LoadDiscovered(repo)
+	if err != nil {
+		t.Fatalf("LoadDiscovered failed: %v", err)
+	}
+	if len(files) != 0 || len(cfg.Groups) != 0 {
+		t.Errorf("Expected no config above the repository root, got %v", files)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	parent := &config.Config{
+		Groups: []config.GroupConfig{
+			{Name: "network", Filename: "network.tf", Patterns: []string{"aws_vpc", "aws_subnet"}},
+			{Name: "compute", Filename: "compute.tf", Patterns: []string{"aws_instance"}},
+		},
+		MinBlocks: 2,
+	}
+	child := &config.Config{
+		Groups: []config.GroupConfig{
+			{Name: "compute", Filename: "servers.tf", Patterns: []string{"aws_instance*"}},
+			{Name: "subnets", Filename: "subnets.tf", Patterns: []string{"aws_subnet"}},
+		},
+		MatchPrecedence: config.PrecedenceSpecific,
+	}
+
+	merged := config.Merge(parent, child)
+	if err := config.ValidateConfig(merged); err != nil {
+		t.Fatalf("Merged config is invalid: %v", err)
+	}
+
+	if len(merged.Groups) != 3 {
+		t.Fatalf("Expected 3 groups, got %+v", merged.Groups)
+	}
+	if merged.Groups[0].Filename != "servers.tf" {
+		t.Errorf("Expected child group to override parent group, got %+v", merged.Groups[0])
+	}
+	network := merged.Groups[2]
+	if network.Name != "network" || len(network.Patterns) != 1 || network.Patterns[0] != "aws_vpc" {
+		t.Errorf("Expected pattern claimed by child to be removed from parent group, got %+v", network)
+	}
+	if merged.MinBlocks != 2 || merged.MatchPrecedence != config.PrecedenceSpecific {
+		t.Errorf("Unexpected scalar settings: min_blocks=%d match_precedence=%s", merged.MinBlocks, merged.MatchPrecedence)
+	}
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// presets are built-in base configurations that can be named in extends.
+var presets = map[string]string{
+	"aws-standard": `
+groups:
+  - name: "network"
+    filename: "network.tf"
+    patterns:
+      - "aws_vpc*"
+      - "aws_subnet*"
+      - "aws_route*"
+      - "aws_internet_gateway*"
+      - "aws_nat_gateway*"
+      - "aws_eip*"
+      - "aws_security_group*"
+      - "aws_network_*"
+  - name: "iam"
+    filename: "iam.tf"
+    patterns:
+      - "aws_iam_*"
+  - name: "compute"
+    filename: "compute.tf"
+    patterns:
+      - "aws_instance*"
+      - "aws_launch_template*"
+      - "aws_autoscaling_*"
+      - "aws_lb*"
+  - name: "storage"
+    filename: "storage.tf"
+    patterns:
+      - "aws_s3_*"
+      - "aws_ebs_*"
+      - "aws_efs_*"
+  - name: "database"
+    filename: "database.tf"
+    patterns:
+      - "aws_db_*"
+      - "aws_rds_*"
+      - "aws_dynamodb_*"
+      - "aws_elasticache_*"
+  - name: "monitoring"
+    filename: "monitoring.tf"
+    patterns:
+      - "aws_cloudwatch_*"
+      - "aws_sns_*"
+`,
+	"hashicorp-style": `
+groups:
+  - name: "versions"
+    filename: "versions.tf"
+    patterns:
+      - "terraform"
+  - name: "main"
+    filename: "main.tf"
+    patterns:
+      - "resource"
+      - "data"
+      - "module"
+      - "locals"
+`,
+}
+
+// PresetNames returns the names of the built-in presets usable in extends.
+func PresetNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolveExtends layers config on top of the bases it extends. Bases are
+// applied in order, so later entries override earlier ones and the extending
+// config overrides them all, using the same rules as Merge. Entries ending in
+// .yaml or .yml are files relative to baseDir; anything else names a preset.
+func resolveExtends(config *Config, baseDir string, chain []string) (*Config, error) {
+	if len(config.Extends) == 0 {
+		return config, nil
+	}
+
+	var base *Config
+	for _, entry := range config.Extends {
+		parent, err := loadBase(entry, baseDir, chain)
+		if err != nil {
+			return nil, fmt.Errorf("extends '%s': %w", entry, err)
+		}
+		base = Merge(base, parent)
+	}
+
+	resolved := Merge(base, config)
+	resolved.Extends = config.Extends
+	return resolved, nil
+}
+
+func loadBase(entry, baseDir string, chain []string) (*Config, error) {
+	if entry == "" {
+		return nil, fmt.Errorf("entry cannot be empty")
+	}
+
+	if strings.HasSuffix(entry, ".yaml") || strings.HasSuffix(entry, ".yml") {
+		path := entry
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		base, err := loadConfigFile(path, chain)
+		if err != nil {
+			return nil, err
+		}
+		base.Extends = nil
+		return base, nil
+	}
+
+	data, exists := presets[entry]
+	if !exists {
+		return nil, fmt.Errorf("unknown preset (available: %s)", strings.Join(PresetNames(), ", "))
+	}
+	return parseConfigData([]byte(data))
+}
@@ -0,0 +1,141 @@
+package config_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tomoya-namekawa/tf-file-organize/internal/config"
+)
+
+func TestLoadConfigExtends(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "shared", "base.yaml"), `
+groups:
+  - name: "network"
+    filename: "network.tf"
+    patterns:
+      - "aws_vpc"
+      - "aws_subnet"
+exclude_files:
+  - "keep-*.tf"
+min_blocks: 2
+`)
+	configPath := filepath.Join(dir, "tf-file-organize.yaml")
+	writeFile(t, configPath, `
+extends:
+  - "shared/base.yaml"
+groups:
+  - name: "subnets"
+    filename: "subnets.tf"
+    patterns:
+      - "aws_subnet"
+exclude_files:
+  - "legacy.tf"
+`)
+
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if len(cfg.Groups) != 2 || cfg.Groups[0].Name != "subnets" || cfg.Groups[1].Name != "network" {
+		t.Fatalf("Unexpected groups: %+v", cfg.Groups)
+	}
+	if got := cfg.Groups[1].Patterns; len(got) != 1 || got[0] != "aws_vpc" {
+		t.Errorf("Expected inherited group to lose claimed pattern, got %v", got)
+	}
+	if len(cfg.ExcludeFiles) != 2 {
+		t.Errorf("Expected exclude files to be combined, got %v", cfg.ExcludeFiles)
+	}
+	if cfg.MinBlocks != 2 {
+		t.Errorf("Expected min_blocks to be inherited, got %d", cfg.MinBlocks)
+	}
+	if len(cfg.Extends) != 1 {
+		t.Errorf("Expected extends to be kept for reference, got %v", cfg.Extends)
+	}
+}
+
+func TestLoadConfigExtendsPreset(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "tf-file-organize.yaml")
+	writeFile(t, configPath, `
+extends:
+  - "aws-standard"
+groups:
+  - name: "security"
+    filename: "security.tf"
+    patterns:
+      - "aws_security_group*"
+`)
+
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	group, _, _ := cfg.FindGroupForCandidates([]string{"aws_security_group"})
+	if group == nil || group.Name != "security" {
+		t.Errorf("Expected local group to override preset, got %+v", group)
+	}
+	group, _, _ = cfg.FindGroupForCandidates([]string{"aws_vpc"})
+	if group == nil || group.Name != "network" {
+		t.Errorf("Expected preset group for aws_vpc, got %+v", group)
+	}
+
+	for _, name := range config.PresetNames() {
+		writeFile(t, configPath, "extends:\n  - \""+name+"\"\n")
+		if _, err := config.LoadConfig(configPath); err != nil {
+			t.Errorf("Preset %s failed to load: %v", name, err)
+		}
+	}
+}
+
+func TestLoadConfigExtendsErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		contains string
+	}{
+		{
+			name: "unknown preset",
+			files: map[string]string{
+				"tf-file-organize.yaml": "extends:\n  - \"gcp-standard\"\n",
+			},
+			contains: "unknown preset",
+		},
+		{
+			name: "missing file",
+			files: map[string]string{
+				"tf-file-organize.yaml": "extends:\n  - \"missing.yaml\"\n",
+			},
+			contains: "extends 'missing.yaml'",
+		},
+		{
+			name: "cycle",
+			files: map[string]string{
+				"tf-file-organize.yaml": "extends:\n  - \"a.yaml\"\n",
+				"a.yaml":                "extends:\n  - \"b.yaml\"\n",
+				"b.yaml":                "extends:\n  - \"a.yaml\"\n",
+			},
+			contains: "circular extends",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				writeFile(t, filepath.Join(dir, name), content)
+			}
+
+			_, err := config.LoadConfig(filepath.Join(dir, "tf-file-organize.yaml"))
+			if err == nil {
+				t.Fatal("Expected error")
+			}
+			if !strings.Contains(err.Error(), tt.contains) {
+				t.Errorf("Expected error containing %q, got %v", tt.contains, err)
+			}
+		})
+	}
+}
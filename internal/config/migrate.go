@@ -0,0 +1,441 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/goccy/go-yaml/token"
+)
+
+// CurrentVersion is the newest configuration format. Version 1 is the original
+// format with 'exclude' and 'overrides'; configs without a version are treated
+// as version 1 when they use those keys.
+const CurrentVersion = 2
+
+// legacyKeys are the version 1 keys replaced in version 2.
+var legacyKeys = []string{"exclude", "overrides"}
+
+// configVersion returns the format version of a decoded document.
+func configVersion(raw map[string]any) (int, error) {
+	value, exists := raw["version"]
+	if !exists {
+		for _, key := range legacyKeys {
+			if _, legacy := raw[key]; legacy {
+				return 1, nil
+			}
+		}
+		return CurrentVersion, nil
+	}
+
+	version, ok := toFloat(value)
+	if !ok || version != float64(int(version)) {
+		return 0, fmt.Errorf("version must be an integer, got %v", value)
+	}
+	if version < 1 || int(version) > CurrentVersion {
+		return 0, fmt.Errorf("unsupported config version %d (this build supports 1 to %d)", int(version), CurrentVersion)
+	}
+	return int(version), nil
+}
+
+// migrateData upgrades an older config document to CurrentVersion in memory.
+// It returns the upgraded YAML and a note for each change made, or data as-is
+// when no migration is needed.
+func migrateData(data []byte) ([]byte, []string, error) {
+	var raw map[string]any
+	if err := yaml.Unmarshal(data, &raw); err != nil || raw == nil {
+		// Malformed or non-mapping documents are reported by the field checks
+		return data, nil, nil
+	}
+
+	version, err := configVersion(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	if version == CurrentVersion {
+		return data, nil, nil
+	}
+
+	var notes []string
+	if exclude, exists := raw["exclude"]; exists {
+		items, ok := exclude.([]any)
+		if !ok {
+			return nil, nil, fmt.Errorf("exclude: expected array, got %s", typeName(exclude))
+		}
+		var existing []any
+		if value := raw["exclude_files"]; value != nil {
+			if existing, ok = value.([]any); !ok {
+				return nil, nil, fmt.Errorf("exclude_files: expected array, got %s", typeName(value))
+			}
+		}
+		raw["exclude_files"] = append(existing, items...)
+		delete(raw, "exclude")
+		notes = append(notes, "'exclude' renamed to 'exclude_files'")
+	}
+
+	if overrides, exists := raw["overrides"]; exists {
+		groups, err := groupList(raw)
+		if err != nil {
+			return nil, nil, err
+		}
+		added, newGroups, err := convertOverrides(overrides, groupFields(groups, "name"), groupFields(groups, "filename"))
+		if err != nil {
+			return nil, nil, err
+		}
+		for i, patterns := range added {
+			group, ok := groups[i].(map[string]any)
+			if !ok {
+				return nil, nil, fmt.Errorf("groups[%d]: expected a mapping", i)
+			}
+			var existing []any
+			if value := group["patterns"]; value != nil {
+				if existing, ok = value.([]any); !ok {
+					return nil, nil, fmt.Errorf("groups[%d].patterns: expected array, got %s", i, typeName(value))
+				}
+			}
+			for _, pattern := range patterns {
+				existing = append(existing, pattern)
+			}
+			group["patterns"] = existing
+		}
+		for _, group := range newGroups {
+			groups = append(groups, group)
+		}
+		raw["groups"] = groups
+		delete(raw, "overrides")
+		notes = append(notes, "'overrides' converted to groups")
+	}
+
+	raw["version"] = CurrentVersion
+	migrated, err := yaml.Marshal(raw)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal migrated config: %w", err)
+	}
+	return migrated, notes, nil
+}
+
+// convertOverrides turns version 1 overrides (block type to file name) into
+// patterns. A file already used by a group receives the pattern in that group,
+// keyed by group index; other files become new groups named after the file.
+func convertOverrides(overrides any, names, filenames []string) (map[int][]string, []GroupConfig, error) {
+	entries, ok := overrides.(map[string]any)
+	if !ok {
+		return nil, nil, fmt.Errorf("overrides: expected object mapping block types to file names, got %s", typeName(overrides))
+	}
+
+	patterns := make([]string, 0, len(entries))
+	for pattern := range entries {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	usedNames := make(map[string]bool)
+	for _, name := range names {
+		usedNames[name] = true
+	}
+
+	added := make(map[int][]string)
+	var newGroups []GroupConfig
+	for _, pattern := range patterns {
+		filename, ok := entries[pattern].(string)
+		if !ok {
+			return nil, nil, fmt.Errorf("overrides.%s: expected string, got %s", pattern, typeName(entries[pattern]))
+		}
+
+		if i := slices.Index(filenames, filename); i >= 0 {
+			added[i] = append(added[i], pattern)
+			continue
+		}
+		if i := slices.IndexFunc(newGroups, func(g GroupConfig) bool { return g.Filename == filename }); i >= 0 {
+			newGroups[i].Patterns = append(newGroups[i].Patterns, pattern)
+			continue
+		}
+
+		name := strings.TrimSuffix(filename, filepath.Ext(filename))
+		unique := name
+		for n := 2; usedNames[unique]; n++ {
+			unique = fmt.Sprintf("%s_%d", name, n)
+		}
+		usedNames[unique] = true
+		newGroups = append(newGroups, GroupConfig{Name: unique, Filename: filename, Patterns: []string{pattern}})
+	}
+
+	return added, newGroups, nil
+}
+
+// groupList returns the groups of a decoded document, checking that each
+// group is a mapping.
+func groupList(raw map[string]any) ([]any, error) {
+	value := raw["groups"]
+	if value == nil {
+		return nil, nil
+	}
+	groups, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("groups: expected array, got %s", typeName(value))
+	}
+	for i, group := range groups {
+		if _, ok := group.(map[string]any); !ok {
+			return nil, fmt.Errorf("groups[%d]: expected a mapping", i)
+		}
+	}
+	return groups, nil
+}
+
+func groupFields(groups []any, field string) []string {
+	values := make([]string, len(groups))
+	for i, group := range groups {
+		if fields, ok := group.(map[string]any); ok {
+			values[i], _ = fields[field].(string)
+		}
+	}
+	return values
+}
+
+// MigrateDocument rewrites a config file in the CurrentVersion format, keeping
+// comments and layout of the parts that do not change. It returns the new
+// content and the changes made; notes are empty when data is already current.
+func MigrateDocument(data []byte) ([]byte, []string, error) {
+	var raw map[string]any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	if raw == nil {
+		return data, nil, nil
+	}
+
+	version, err := configVersion(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, hasVersion := raw["version"]
+	if version == CurrentVersion && hasVersion {
+		return data, nil, nil
+	}
+
+	file, err := parser.ParseBytes(data, parser.ParseComments)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	if len(file.Docs) != 1 {
+		return nil, nil, fmt.Errorf("expected a single YAML document, found %d", len(file.Docs))
+	}
+	root, ok := file.Docs[0].Body.(*ast.MappingNode)
+	if !ok {
+		return nil, nil, fmt.Errorf("config must be a mapping")
+	}
+
+	var notes []string
+	if version < CurrentVersion {
+		if notes, err = migrateNodes(root, raw); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := setVersionNode(root); err != nil {
+		return nil, nil, err
+	}
+	notes = append(notes, fmt.Sprintf("version set to %d", CurrentVersion))
+
+	migrated := []byte(strings.TrimRight(file.String(), "\n") + "\n")
+	if _, err := parseConfigData(migrated); err != nil {
+		return nil, nil, fmt.Errorf("migrated config is invalid: %w", err)
+	}
+	return migrated, notes, nil
+}
+
+func migrateNodes(root *ast.MappingNode, raw map[string]any) ([]string, error) {
+	var notes []string
+
+	if exclude := findValue(root, "exclude"); exclude != nil {
+		if excludeFiles := findValue(root, "exclude_files"); excludeFiles != nil {
+			target, ok := excludeFiles.Value.(*ast.SequenceNode)
+			items, itemsOK := exclude.Value.(*ast.SequenceNode)
+			if !ok || !itemsOK {
+				return nil, fmt.Errorf("exclude and exclude_files must both be lists to be combined")
+			}
+			target.Merge(items)
+			removeValue(root, exclude)
+			notes = append(notes, "'exclude' merged into 'exclude_files'")
+		} else {
+			key, ok := exclude.Key.(*ast.StringNode)
+			if !ok {
+				return nil, fmt.Errorf("unexpected key type for 'exclude'")
+			}
+			key.Value = "exclude_files"
+			key.Token.Value = "exclude_files"
+			notes = append(notes, "'exclude' renamed to 'exclude_files'")
+		}
+	}
+
+	if overrides := findValue(root, "overrides"); overrides != nil {
+		groups, err := groupList(raw)
+		if err != nil {
+			return nil, err
+		}
+		added, newGroups, err := convertOverrides(raw["overrides"], groupFields(groups, "name"), groupFields(groups, "filename"))
+		if err != nil {
+			return nil, err
+		}
+		if err := addOverrideNodes(root, added, newGroups); err != nil {
+			return nil, err
+		}
+		removeValue(root, overrides)
+		notes = append(notes, "'overrides' converted to groups")
+	}
+
+	return notes, nil
+}
+
+func addOverrideNodes(root *ast.MappingNode, added map[int][]string, newGroups []GroupConfig) error {
+	groupsValue := findValue(root, "groups")
+
+	for i, patterns := range added {
+		seq, ok := groupsValue.Value.(*ast.SequenceNode)
+		if !ok || i >= len(seq.Values) {
+			return fmt.Errorf("groups: unexpected layout")
+		}
+		group, ok := seq.Values[i].(*ast.MappingNode)
+		if !ok {
+			return fmt.Errorf("groups[%d]: unexpected layout", i)
+		}
+		patternsValue := findValue(group, "patterns")
+		if patternsValue == nil {
+			return fmt.Errorf("groups[%d]: patterns not found", i)
+		}
+		target, ok := patternsValue.Value.(*ast.SequenceNode)
+		if !ok {
+			return fmt.Errorf("groups[%d].patterns: unexpected layout", i)
+		}
+		snippet, err := parseSnippet("patterns:\n" + quotedList(patterns, "  "))
+		if err != nil {
+			return err
+		}
+		target.Merge(findValue(snippet, "patterns").Value.(*ast.SequenceNode))
+	}
+
+	if len(newGroups) == 0 {
+		return nil
+	}
+	var text strings.Builder
+	text.WriteString("groups:\n")
+	for _, group := range newGroups {
+		fmt.Fprintf(&text, "  - name: %s\n    filename: %s\n    patterns:\n", strconv.Quote(group.Name), strconv.Quote(group.Filename))
+		text.WriteString(quotedList(group.Patterns, "      "))
+	}
+	snippet, err := parseSnippet(text.String())
+	if err != nil {
+		return err
+	}
+	if groupsValue == nil {
+		root.Values = append(root.Values, findValue(snippet, "groups"))
+		return nil
+	}
+	target, ok := groupsValue.Value.(*ast.SequenceNode)
+	if !ok {
+		return fmt.Errorf("groups: unexpected layout")
+	}
+	target.Merge(findValue(snippet, "groups").Value.(*ast.SequenceNode))
+	return nil
+}
+
+// setVersionNode sets version to CurrentVersion, adding it as the first key.
+// A leading comment block separated from the first key by a blank line stays
+// at the top of the file.
+func setVersionNode(root *ast.MappingNode) error {
+	snippet, err := parseSnippet(fmt.Sprintf("version: %d\n", CurrentVersion))
+	if err != nil {
+		return err
+	}
+	versionValue := findValue(snippet, "version")
+
+	if existing := findValue(root, "version"); existing != nil {
+		existing.Value = versionValue.Value
+		return nil
+	}
+
+	if len(root.Values) > 0 {
+		first := root.Values[0]
+		if comment := first.GetComment(); comment != nil {
+			header, rest := splitHeaderComment(comment)
+			if len(header) > 0 {
+				if err := versionValue.SetComment(ast.CommentGroup(header)); err != nil {
+					return err
+				}
+				var restGroup *ast.CommentGroupNode
+				if len(rest) > 0 {
+					restGroup = ast.CommentGroup(rest)
+				}
+				if err := first.SetComment(restGroup); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	root.Values = append([]*ast.MappingValueNode{versionValue}, root.Values...)
+	return nil
+}
+
+// splitHeaderComment splits comment lines at the last blank line.
+func splitHeaderComment(comment *ast.CommentGroupNode) (header, rest []*token.Token) {
+	split := 0
+	for i := 1; i < len(comment.Comments); i++ {
+		if comment.Comments[i].Token.Position.Line > comment.Comments[i-1].Token.Position.Line+1 {
+			split = i
+		}
+	}
+	for i, c := range comment.Comments {
+		if i < split {
+			header = append(header, c.Token)
+		} else {
+			rest = append(rest, c.Token)
+		}
+	}
+	return header, rest
+}
+
+func quotedList(values []string, indent string) string {
+	var text strings.Builder
+	for _, value := range values {
+		fmt.Fprintf(&text, "%s- %s\n", indent, strconv.Quote(value))
+	}
+	return text.String()
+}
+
+// parseSnippet parses YAML written in the style of the documentation examples
+// so that inserted nodes match hand-written configs.
+func parseSnippet(text string) (*ast.MappingNode, error) {
+	file, err := parser.ParseBytes([]byte(text), 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse migrated config: %w", err)
+	}
+	mapping, ok := file.Docs[0].Body.(*ast.MappingNode)
+	if !ok {
+		return nil, fmt.Errorf("failed to parse migrated config: unexpected layout")
+	}
+	return mapping, nil
+}
+
+func findValue(mapping *ast.MappingNode, key string) *ast.MappingValueNode {
+	for _, value := range mapping.Values {
+		if value.Key.String() == key {
+			return value
+		}
+	}
+	return nil
+}
+
+func removeValue(mapping *ast.MappingNode, target *ast.MappingValueNode) {
+	values := mapping.Values[:0]
+	for _, value := range mapping.Values {
+		if value != target {
+			values = append(values, value)
+		}
+	}
+	mapping.Values = values
+}
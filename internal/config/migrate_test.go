@@ -0,0 +1,128 @@
+package config_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tomoya-namekawa/tf-file-organize/internal/config"
+)
+
+const legacyConfig = `# Team configuration
+
+# Network resources
+groups:
+  - name: "network" # shared VPC
+    filename: "network.tf"
+    patterns:
+      - "aws_vpc"
+
+# Files to leave alone
+exclude:
+  - "*.backup"
+overrides:
+  variable: "vars.tf"
+  output: "network.tf"
+`
+
+func TestLoadConfigMigratesLegacyKeys(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "tf-file-organize.yaml")
+	writeFile(t, configPath, legacyConfig)
+
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if cfg.Version != config.CurrentVersion {
+		t.Errorf("Expected version %d, got %d", config.CurrentVersion, cfg.Version)
+	}
+	if len(cfg.ExcludeFiles) != 1 || cfg.ExcludeFiles[0] != "*.backup" {
+		t.Errorf("Expected exclude to become exclude_files, got %v", cfg.ExcludeFiles)
+	}
+	if group := cfg.FindGroupForResource("output"); group == nil || group.Name != "network" {
+		t.Errorf("Expected output override to join the network group, got %+v", group)
+	}
+	if group := cfg.FindGroupForResource("variable"); group == nil || group.Filename != "vars.tf" {
+		t.Errorf("Expected variable override to become a group, got %+v", group)
+	}
+	if len(cfg.Migrations) != 2 || !strings.HasPrefix(cfg.Migrations[0], configPath) {
+		t.Errorf("Expected migration notes for %s, got %v", configPath, cfg.Migrations)
+	}
+}
+
+func TestLoadConfigUnsupportedVersion(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "tf-file-organize.yaml")
+	writeFile(t, configPath, "version: 99\n")
+
+	_, err := config.LoadConfig(configPath)
+	if err == nil || !strings.Contains(err.Error(), "unsupported config version 99") {
+		t.Errorf("Expected unsupported version error, got %v", err)
+	}
+}
+
+func TestLoadConfigMalformedLegacyConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"group is not a mapping", "groups:\n  - \"x\"\noverrides:\n  aws_s3: \"\"\n", "groups[0]: expected a mapping"},
+		{"groups is not a list", "groups: \"x\"\noverrides:\n  aws_s3: \"s3.tf\"\n", "groups: expected array"},
+		{"patterns is not a list", "groups:\n  - name: \"s3\"\n    filename: \"s3.tf\"\n    patterns: \"x\"\noverrides:\n  aws_s3: \"s3.tf\"\n", "groups[0].patterns: expected array"},
+		{"exclude_files is not a list", "exclude_files: \"x\"\nexclude:\n  - \"*.bak\"\n", "exclude_files: expected array"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "tf-file-organize.yaml")
+			writeFile(t, configPath, tt.content)
+
+			_, err := config.LoadConfig(configPath)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestMigrateDocument(t *testing.T) {
+	migrated, notes, err := config.MigrateDocument([]byte(legacyConfig))
+	if err != nil {
+		t.Fatalf("MigrateDocument failed: %v", err)
+	}
+	if len(notes) != 3 {
+		t.Errorf("Expected 3 notes, got %v", notes)
+	}
+
+	expected := `# Team configuration
+version: 2
+
+# Network resources
+groups:
+  - name: "network" # shared VPC
+    filename: "network.tf"
+    patterns:
+      - "aws_vpc"
+      - "output"
+  - name: "vars"
+    filename: "vars.tf"
+    patterns:
+      - "variable"
+
+# Files to leave alone
+exclude_files:
+  - "*.backup"
+`
+	if string(migrated) != expected {
+		t.Errorf("Unexpected migrated document:\n%s", migrated)
+	}
+
+	again, notes, err := config.MigrateDocument(migrated)
+	if err != nil {
+		t.Fatalf("MigrateDocument failed on migrated config: %v", err)
+	}
+	if len(notes) != 0 || string(again) != string(migrated) {
+		t.Errorf("Expected migration to be idempotent, got notes %v", notes)
+	}
+}
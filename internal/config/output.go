@@ -0,0 +1,142 @@
+package config
+
+import (
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strconv"
+
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/types"
+)
+
+// Line ending settings for generated files.
+const (
+	LineEndingLF   = "lf"
+	LineEndingCRLF = "crlf"
+)
+
+const maxHeaderLength = 4096
+
+// OutputConfig controls the layout of generated files. Unset layout fields keep
+// the layout detected from the source files.
+type OutputConfig struct {
+	LineEnding string `yaml:"line_ending,omitempty"`
+	BOM        *bool  `yaml:"bom,omitempty"`
+	FileMode   string `yaml:"file_mode,omitempty"` // octal, e.g. "0644"
+
+	// Header is a comment written at the top of every generated file
+	Header string `yaml:"header,omitempty"`
+	// SectionSeparators adds a "# --- <type> ---" comment before each block
+	// type in files holding several types
+	SectionSeparators *bool `yaml:"section_separators,omitempty"`
+
+	// LicenseHeader copies the file-level comment at the top of the source
+	// files, such as a license banner, to every generated file
+	LicenseHeader *bool `yaml:"license_header,omitempty"`
+	// LicensePattern restricts LicenseHeader to comments matching this regex
+	LicensePattern string `yaml:"license_pattern,omitempty"`
+}
+
+// LicenseHeaderPattern returns whether license headers are propagated and the
+// pattern they must match, if any. Setting a pattern enables propagation.
+func (o *OutputConfig) LicenseHeaderPattern() (bool, *regexp.Regexp) {
+	if o == nil {
+		return false, nil
+	}
+	enabled := o.LicensePattern != "" || (o.LicenseHeader != nil && *o.LicenseHeader)
+	if o.LicensePattern == "" {
+		return enabled, nil
+	}
+	pattern, err := regexp.Compile(o.LicensePattern)
+	if err != nil {
+		return false, nil // rejected by validateOutput
+	}
+	return enabled, pattern
+}
+
+// Apply returns format with the configured overrides applied.
+func (o *OutputConfig) Apply(format types.FileFormat) types.FileFormat {
+	if o == nil {
+		return format
+	}
+	switch o.LineEnding {
+	case LineEndingLF:
+		format.CRLF = false
+	case LineEndingCRLF:
+		format.CRLF = true
+	}
+	if o.BOM != nil {
+		format.BOM = *o.BOM
+	}
+	if mode, err := parseFileMode(o.FileMode); err == nil && mode != 0 {
+		format.Mode = mode
+	}
+	return format
+}
+
+func validateOutput(output *OutputConfig) error {
+	if output == nil {
+		return nil
+	}
+	switch output.LineEnding {
+	case "", LineEndingLF, LineEndingCRLF:
+	default:
+		return fmt.Errorf("output.line_ending must be '%s' or '%s': %s", LineEndingLF, LineEndingCRLF, output.LineEnding)
+	}
+	if _, err := parseFileMode(output.FileMode); err != nil {
+		return fmt.Errorf("output.file_mode: %w", err)
+	}
+	if len(output.Header) > maxHeaderLength {
+		return fmt.Errorf("output.header too long (max %d chars)", maxHeaderLength)
+	}
+	if output.LicensePattern != "" {
+		if _, err := regexp.Compile(output.LicensePattern); err != nil {
+			return fmt.Errorf("output.license_pattern: %w", err)
+		}
+	}
+	return nil
+}
+
+func parseFileMode(value string) (fs.FileMode, error) {
+	if value == "" {
+		return 0, nil
+	}
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid octal permissions: %s", value)
+	}
+	return fs.FileMode(mode), nil
+}
+
+// mergeOutput overrides parent output settings with those set in child.
+func mergeOutput(parent, child *OutputConfig) *OutputConfig {
+	if parent == nil {
+		return child
+	}
+	if child == nil {
+		return parent
+	}
+	merged := *parent
+	if child.LineEnding != "" {
+		merged.LineEnding = child.LineEnding
+	}
+	if child.BOM != nil {
+		merged.BOM = child.BOM
+	}
+	if child.FileMode != "" {
+		merged.FileMode = child.FileMode
+	}
+	if child.Header != "" {
+		merged.Header = child.Header
+	}
+	if child.SectionSeparators != nil {
+		merged.SectionSeparators = child.SectionSeparators
+	}
+	if child.LicenseHeader != nil {
+		merged.LicenseHeader = child.LicenseHeader
+	}
+	if child.LicensePattern != "" {
+		merged.LicensePattern = child.LicensePattern
+	}
+	return &merged
+}
@@ -0,0 +1,223 @@
+package config
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+//go:embed schema.json
+var schemaJSON []byte
+
+// Schema returns the JSON Schema describing the configuration file.
+func Schema() []byte {
+	return schemaJSON
+}
+
+// SchemaError is a schema violation at a path such as groups[2].patterns[0].
+type SchemaError struct {
+	Path    string
+	Message string
+}
+
+func (e SchemaError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// schemaNode is the subset of JSON Schema used by schema.json.
+type schemaNode struct {
+	Ref                  string                 `json:"$ref"`
+	Type                 string                 `json:"type"`
+	Properties           map[string]*schemaNode `json:"properties"`
+	AdditionalProperties *bool                  `json:"additionalProperties"`
+	Required             []string               `json:"required"`
+	Items                *schemaNode            `json:"items"`
+	MinItems             *int                   `json:"minItems"`
+	Enum                 []string               `json:"enum"`
+	Minimum              *float64               `json:"minimum"`
+	MinLength            *int                   `json:"minLength"`
+	MaxLength            *int                   `json:"maxLength"`
+	Pattern              string                 `json:"pattern"`
+	Definitions          map[string]*schemaNode `json:"definitions"`
+}
+
+var configSchema = mustParseSchema(schemaJSON)
+
+func mustParseSchema(data []byte) *schemaNode {
+	var node schemaNode
+	if err := json.Unmarshal(data, &node); err != nil {
+		panic(fmt.Sprintf("invalid embedded config schema: %v", err))
+	}
+	return &node
+}
+
+// validateSchema checks a decoded YAML document against the config schema and
+// returns every violation found.
+func validateSchema(value any) []SchemaError {
+	var errs []SchemaError
+	configSchema.validate(configSchema, value, "", &errs)
+	return errs
+}
+
+func (n *schemaNode) validate(root *schemaNode, value any, path string, errs *[]SchemaError) {
+	if n.Ref != "" {
+		n = root.resolve(n.Ref)
+	}
+
+	if n.Type != "" && !matchesType(n.Type, value) {
+		*errs = append(*errs, SchemaError{path, fmt.Sprintf("expected %s, got %s", n.Type, typeName(value))})
+		return
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		n.validateObject(root, v, path, errs)
+	case []any:
+		if n.MinItems != nil && len(v) < *n.MinItems {
+			*errs = append(*errs, SchemaError{path, fmt.Sprintf("must contain at least %d item(s)", *n.MinItems)})
+		}
+		if n.Items != nil {
+			for i, item := range v {
+				n.Items.validate(root, item, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+	case string:
+		n.validateString(v, path, errs)
+	}
+
+	if n.Minimum != nil {
+		if number, ok := toFloat(value); ok && number < *n.Minimum {
+			*errs = append(*errs, SchemaError{path, fmt.Sprintf("must be >= %v", *n.Minimum)})
+		}
+	}
+}
+
+func (n *schemaNode) validateObject(root *schemaNode, object map[string]any, path string, errs *[]SchemaError) {
+	for _, field := range n.Required {
+		if _, exists := object[field]; !exists {
+			*errs = append(*errs, SchemaError{path, fmt.Sprintf("missing required field '%s'", field)})
+		}
+	}
+
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		fieldPath := key
+		if path != "" {
+			fieldPath = path + "." + key
+		}
+		property, exists := n.Properties[key]
+		if !exists {
+			if n.AdditionalProperties != nil && !*n.AdditionalProperties {
+				*errs = append(*errs, SchemaError{fieldPath, "unknown field"})
+			}
+			continue
+		}
+		property.validate(root, object[key], fieldPath, errs)
+	}
+}
+
+func (n *schemaNode) validateString(value, path string, errs *[]SchemaError) {
+	length := utf8.RuneCountInString(value)
+	if n.MinLength != nil && length < *n.MinLength {
+		if *n.MinLength == 1 {
+			*errs = append(*errs, SchemaError{path, "cannot be empty"})
+		} else {
+			*errs = append(*errs, SchemaError{path, fmt.Sprintf("must be at least %d characters", *n.MinLength)})
+		}
+	}
+	if n.MaxLength != nil && length > *n.MaxLength {
+		*errs = append(*errs, SchemaError{path, fmt.Sprintf("too long (max %d chars)", *n.MaxLength)})
+	}
+	if len(n.Enum) > 0 && !slices.Contains(n.Enum, value) {
+		*errs = append(*errs, SchemaError{path, fmt.Sprintf("must be one of '%s', got '%s'", strings.Join(n.Enum, "', '"), value)})
+	}
+	if n.Pattern != "" && value != "" && !regexp.MustCompile(n.Pattern).MatchString(value) {
+		*errs = append(*errs, SchemaError{path, fmt.Sprintf("must match pattern %s", n.Pattern)})
+	}
+}
+
+func (n *schemaNode) resolve(ref string) *schemaNode {
+	name := strings.TrimPrefix(ref, "#/definitions/")
+	node, exists := n.Definitions[name]
+	if !exists {
+		panic(fmt.Sprintf("invalid embedded config schema: unresolved $ref %s", ref))
+	}
+	if node.Ref != "" {
+		return n.resolve(node.Ref)
+	}
+	return node
+}
+
+func matchesType(schemaType string, value any) bool {
+	switch schemaType {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "integer":
+		number, ok := toFloat(value)
+		return ok && number == math.Trunc(number)
+	case "number":
+		_, ok := toFloat(value)
+		return ok
+	}
+	return true
+}
+
+func typeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	}
+	if number, ok := toFloat(value); ok {
+		if number == math.Trunc(number) {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func toFloat(value any) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
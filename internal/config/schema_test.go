@@ -0,0 +1,128 @@
+package config_test
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/tomoya-namekawa/tf-file-organize/internal/config"
+)
+
+func TestSchemaMatchesConfig(t *testing.T) {
+	var schema struct {
+		Properties  map[string]any `json:"properties"`
+		Definitions struct {
+			Group struct {
+				Properties map[string]any `json:"properties"`
+			} `json:"group"`
+			Output struct {
+				Properties map[string]any `json:"properties"`
+			} `json:"output"`
+		} `json:"definitions"`
+	}
+	if err := json.Unmarshal(config.Schema(), &schema); err != nil {
+		t.Fatalf("Schema is not valid JSON: %v", err)
+	}
+
+	assertSameKeys(t, "config", yamlKeys(reflect.TypeOf(config.Config{})), schema.Properties)
+	assertSameKeys(t, "group", yamlKeys(reflect.TypeOf(config.GroupConfig{})), schema.Definitions.Group.Properties)
+	assertSameKeys(t, "output", yamlKeys(reflect.TypeOf(config.OutputConfig{})), schema.Definitions.Output.Properties)
+}
+
+func yamlKeys(typ reflect.Type) []string {
+	var keys []string
+	for i := range typ.NumField() {
+		tag := typ.Field(i).Tag.Get("yaml")
+		if name, _, _ := strings.Cut(tag, ","); name != "" && name != "-" {
+			keys = append(keys, name)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func assertSameKeys(t *testing.T, what string, fields []string, properties map[string]any) {
+	t.Helper()
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if !reflect.DeepEqual(fields, keys) {
+		t.Errorf("Schema %s properties %v do not match struct fields %v", what, keys, fields)
+	}
+}
+
+func TestLoadConfigSchemaErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		yaml     string
+		contains []string
+	}{
+		{
+			name: "wrong pattern type",
+			yaml: `
+groups:
+  - name: "a"
+    filename: "a.tf"
+    patterns: ["aws_a"]
+  - name: "b"
+    filename: "b.tf"
+    patterns: ["aws_b"]
+  - name: "c"
+    filename: "c.tf"
+    patterns:
+      - ["nested"]
+`,
+			contains: []string{"groups[2].patterns[0]: expected string, got array"},
+		},
+		{
+			name: "missing required and unknown field",
+			yaml: `
+groups:
+  - name: "compute"
+    pattern: "aws_instance"
+`,
+			contains: []string{
+				"groups[0]: missing required field 'filename'",
+				"groups[0]: missing required field 'patterns'",
+				"groups[0].pattern: unknown field",
+			},
+		},
+		{
+			name:     "enum",
+			yaml:     "match_precedence: \"longest\"\n",
+			contains: []string{"match_precedence: must be one of 'first', 'specific', got 'longest'"},
+		},
+		{
+			name:     "minimum",
+			yaml:     "min_blocks: -1\n",
+			contains: []string{"min_blocks: must be >= 0"},
+		},
+		{
+			name:     "filename characters",
+			yaml:     "catch_all_file: \"dir/main.tf\"\n",
+			contains: []string{"catch_all_file: must match pattern"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "tf-file-organize.yaml")
+			writeFile(t, configPath, tt.yaml)
+
+			_, err := config.LoadConfig(configPath)
+			if err == nil {
+				t.Fatal("Expected error")
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Expected error containing %q, got %v", want, err)
+				}
+			}
+		})
+	}
+}
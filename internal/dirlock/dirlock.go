@@ -0,0 +1,170 @@
+// Package dirlock provides the advisory lock file that keeps two runs from
+// organizing the same directory at the same time.
+package dirlock
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileName is the lock file created in a directory while it is organized.
+const FileName = ".tf-file-organize.run.lock"
+
+// StaleAfter is the age after which a lock whose holder cannot be checked, taken
+// on another host or unreadable, is considered abandoned. Runs take seconds, so
+// an older lock most likely outlived its run.
+const StaleAfter = time.Hour
+
+// maxAttempts bounds the retries when a stale lock is replaced concurrently.
+const maxAttempts = 3
+
+// Holder identifies the run holding a lock.
+type Holder struct {
+	PID     int       `json:"pid"`
+	Host    string    `json:"host"`
+	Started time.Time `json:"started"`
+}
+
+func (h *Holder) String() string {
+	return fmt.Sprintf("pid %d on %s, started %s", h.PID, h.Host, h.Started.Format(time.RFC3339))
+}
+
+// stale reports whether the run holding the lock is gone. A lock taken on this
+// host is stale once its process no longer exists, however long the run takes;
+// other locks are stale once older than StaleAfter.
+func (h *Holder) stale(now time.Time, host string) bool {
+	if h.Host == host && canCheckProcess {
+		return !processAlive(h.PID)
+	}
+	return now.Sub(h.Started) > StaleAfter
+}
+
+// LockedError reports a directory locked by another run.
+type LockedError struct {
+	Path   string  // Lock file
+	Holder *Holder // Run holding the lock; nil if the lock file is unreadable
+}
+
+func (e *LockedError) Error() string {
+	holder := "an unknown process (unreadable lock file)"
+	if e.Holder != nil {
+		holder = "another tf-file-organize run (" + e.Holder.String() + ")"
+	}
+	return fmt.Sprintf("%s is being organized by %s; remove %s if that run is no longer active",
+		filepath.Dir(e.Path), holder, e.Path)
+}
+
+// Lock is a lock file held by this process.
+type Lock struct {
+	path string
+	data []byte
+}
+
+// Acquire creates the lock file in dir, creating dir if needed. A stale lock
+// is replaced and its holder returned, so the caller can report it. A lock
+// held by a live run yields a *LockedError.
+func Acquire(dir string) (lock *Lock, stale *Holder, err error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, nil, fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	host, _ := os.Hostname()
+	path := filepath.Join(dir, FileName)
+	for range maxAttempts {
+		holder := Holder{PID: os.Getpid(), Host: host, Started: time.Now().UTC()}
+		data, err := json.Marshal(holder)
+		if err != nil {
+			return nil, nil, err
+		}
+		created, err := create(path, data)
+		if err != nil {
+			return nil, nil, err
+		}
+		if created {
+			return &Lock{path: path, data: data}, stale, nil
+		}
+
+		existing, current, err := read(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue // released in the meantime
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if current == nil || !current.stale(time.Now(), host) {
+			return nil, nil, &LockedError{Path: path, Holder: current}
+		}
+
+		// Remove the stale lock only if no other run replaced it meanwhile
+		if again, _, err := read(path); err == nil && bytes.Equal(again, existing) {
+			if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return nil, nil, fmt.Errorf("failed to remove stale lock %s: %w", path, err)
+			}
+			stale = current
+		}
+	}
+	return nil, nil, fmt.Errorf("failed to acquire lock %s: it keeps changing", path)
+}
+
+// Release removes the lock file, unless another run replaced it after
+// considering this lock stale.
+func (l *Lock) Release() error {
+	data, _, err := read(l.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(data, l.data) {
+		return fmt.Errorf("lock %s was taken over by another run", l.path)
+	}
+	if err := os.Remove(l.path); err != nil {
+		return fmt.Errorf("failed to remove lock %s: %w", l.path, err)
+	}
+	return nil
+}
+
+// create writes a new lock file and reports false if one already exists.
+func create(path string, data []byte) (bool, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600) //nolint:gosec // path is the lock file of the output directory
+	if errors.Is(err, fs.ErrExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create lock %s: %w", path, err)
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return false, fmt.Errorf("failed to write lock %s: %w", path, err)
+	}
+	return true, nil
+}
+
+// read returns the content of the lock file and its holder, which is nil if
+// the content cannot be parsed. An unparsable lock older than StaleAfter gets
+// a zero holder, which is stale.
+func read(path string) ([]byte, *Holder, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // path is the lock file of the output directory
+	if err != nil {
+		return nil, nil, err
+	}
+	var holder Holder
+	if err := json.Unmarshal(data, &holder); err == nil && holder.PID > 0 {
+		return data, &holder, nil
+	}
+	if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > StaleAfter {
+		return data, &Holder{Started: info.ModTime()}, nil
+	}
+	return data, nil, nil
+}
@@ -0,0 +1,192 @@
+package dirlock_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tomoya-namekawa/tf-file-organize/internal/dirlock"
+)
+
+func writeLock(t *testing.T, dir string, holder dirlock.Holder) {
+	t.Helper()
+	data, err := json.Marshal(holder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, dirlock.FileName), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func hostname(t *testing.T) string {
+	t.Helper()
+	host, err := os.Hostname()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return host
+}
+
+func TestAcquireAndRelease(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "out")
+
+	lock, stale, err := dirlock.Acquire(dir)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if stale != nil {
+		t.Errorf("Expected no stale lock, got %v", stale)
+	}
+	if _, err := os.Stat(filepath.Join(dir, dirlock.FileName)); err != nil {
+		t.Fatalf("Expected lock file: %v", err)
+	}
+
+	if _, _, err := dirlock.Acquire(dir); err == nil {
+		t.Fatal("Expected the second Acquire to fail")
+	}
+
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, dirlock.FileName)); !os.IsNotExist(err) {
+		t.Errorf("Expected lock file to be removed, got %v", err)
+	}
+
+	lock, _, err = dirlock.Acquire(dir)
+	if err != nil {
+		t.Fatalf("Acquire after Release failed: %v", err)
+	}
+	_ = lock.Release()
+}
+
+func TestAcquireNamesHolder(t *testing.T) {
+	dir := t.TempDir()
+	started := time.Now().UTC().Truncate(time.Second)
+	writeLock(t, dir, dirlock.Holder{PID: os.Getpid(), Host: hostname(t), Started: started})
+
+	_, _, err := dirlock.Acquire(dir)
+	var locked *dirlock.LockedError
+	if !errors.As(err, &locked) {
+		t.Fatalf("Expected LockedError, got %v", err)
+	}
+	if locked.Holder == nil || locked.Holder.PID != os.Getpid() {
+		t.Errorf("Expected holder pid %d, got %v", os.Getpid(), locked.Holder)
+	}
+	for _, want := range []string{
+		fmt.Sprintf("pid %d", os.Getpid()),
+		hostname(t),
+		started.Format(time.RFC3339),
+		filepath.Join(dir, dirlock.FileName),
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to contain %q, got %q", want, err)
+		}
+	}
+}
+
+func TestAcquireReplacesStaleLock(t *testing.T) {
+	exited := exec.Command("true")
+	if err := exited.Run(); err != nil {
+		t.Skipf("cannot run a process: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		holder dirlock.Holder
+	}{
+		{
+			name:   "process gone",
+			holder: dirlock.Holder{PID: exited.Process.Pid, Host: hostname(t), Started: time.Now()},
+		},
+		{
+			name:   "too old",
+			holder: dirlock.Holder{PID: os.Getpid(), Host: "elsewhere", Started: time.Now().Add(-2 * dirlock.StaleAfter)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeLock(t, dir, tt.holder)
+
+			lock, stale, err := dirlock.Acquire(dir)
+			if err != nil {
+				t.Fatalf("Acquire failed: %v", err)
+			}
+			defer func() { _ = lock.Release() }()
+			if stale == nil || stale.PID != tt.holder.PID {
+				t.Errorf("Expected stale holder pid %d, got %v", tt.holder.PID, stale)
+			}
+		})
+	}
+}
+
+func TestAcquireKeepsOldLockOfLiveProcess(t *testing.T) {
+	dir := t.TempDir()
+	// A run may take longer than StaleAfter; its lock stands while it runs
+	writeLock(t, dir, dirlock.Holder{PID: os.Getpid(), Host: hostname(t), Started: time.Now().Add(-2 * dirlock.StaleAfter)})
+
+	_, _, err := dirlock.Acquire(dir)
+	var locked *dirlock.LockedError
+	if !errors.As(err, &locked) {
+		t.Fatalf("Expected LockedError, got %v", err)
+	}
+}
+
+func TestAcquireKeepsLiveLockOfOtherHost(t *testing.T) {
+	dir := t.TempDir()
+	// The process ID means nothing on another host, so only age makes it stale
+	writeLock(t, dir, dirlock.Holder{PID: 999999, Host: "elsewhere", Started: time.Now()})
+
+	_, _, err := dirlock.Acquire(dir)
+	var locked *dirlock.LockedError
+	if !errors.As(err, &locked) {
+		t.Fatalf("Expected LockedError, got %v", err)
+	}
+}
+
+func TestAcquireUnreadableLock(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, dirlock.FileName)
+	if err := os.WriteFile(path, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err := dirlock.Acquire(dir)
+	var locked *dirlock.LockedError
+	if !errors.As(err, &locked) || locked.Holder != nil {
+		t.Fatalf("Expected LockedError without holder, got %v", err)
+	}
+
+	old := time.Now().Add(-2 * dirlock.StaleAfter)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	lock, _, err := dirlock.Acquire(dir)
+	if err != nil {
+		t.Fatalf("Expected an old unreadable lock to be replaced, got %v", err)
+	}
+	_ = lock.Release()
+}
+
+func TestReleaseKeepsReplacedLock(t *testing.T) {
+	dir := t.TempDir()
+	lock, _, err := dirlock.Acquire(dir)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	writeLock(t, dir, dirlock.Holder{PID: 1, Host: "elsewhere", Started: time.Now()})
+
+	if err := lock.Release(); err == nil {
+		t.Error("Expected Release to report the lock was taken over")
+	}
+	if _, err := os.Stat(filepath.Join(dir, dirlock.FileName)); err != nil {
+		t.Errorf("Expected the other run's lock to be kept: %v", err)
+	}
+}
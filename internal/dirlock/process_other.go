@@ -0,0 +1,11 @@
+//go:build !unix
+
+package dirlock
+
+// canCheckProcess is false: without a portable check, locks are only
+// considered stale once older than StaleAfter.
+const canCheckProcess = false
+
+func processAlive(int) bool {
+	return true
+}
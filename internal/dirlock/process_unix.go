@@ -0,0 +1,18 @@
+//go:build unix
+
+package dirlock
+
+import (
+	"errors"
+	"syscall"
+)
+
+// canCheckProcess reports whether processAlive can tell if a process exists.
+const canCheckProcess = true
+
+// processAlive reports whether a process with the ID exists on this host.
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	// EPERM: the process exists but belongs to another user
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
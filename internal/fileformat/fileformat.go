@@ -0,0 +1,82 @@
+// Package fileformat detects and reproduces the on-disk layout of Terraform
+// files: line endings, UTF-8 byte order mark and permissions.
+package fileformat
+
+import (
+	"bytes"
+	"io/fs"
+
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/types"
+)
+
+// DefaultMode is used for generated files when no source mode is known.
+const DefaultMode fs.FileMode = 0600
+
+var bom = []byte{0xEF, 0xBB, 0xBF}
+
+// Detect returns the layout of content. A file uses CRLF when most of its
+// line breaks are \r\n.
+func Detect(content []byte, mode fs.FileMode) types.FileFormat {
+	crlf := bytes.Count(content, []byte("\r\n"))
+	lf := bytes.Count(content, []byte("\n")) - crlf
+	return types.FileFormat{
+		CRLF: crlf > lf,
+		BOM:  bytes.HasPrefix(content, bom),
+		Mode: mode.Perm(),
+	}
+}
+
+// Decode strips the byte order mark and converts line endings to \n, the form
+// used while parsing and generating content.
+func Decode(content []byte) []byte {
+	content = bytes.TrimPrefix(content, bom)
+	return bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+}
+
+// Encode converts content generated with \n line endings to format.
+func Encode(content []byte, format types.FileFormat) []byte {
+	if format.CRLF {
+		content = bytes.ReplaceAll(content, []byte("\n"), []byte("\r\n"))
+	}
+	if format.BOM {
+		content = append(append([]byte{}, bom...), content...)
+	}
+	return content
+}
+
+// Dominant returns the layout used by most of formats. Ties favor \n line
+// endings, no byte order mark, and the mode seen first.
+func Dominant(formats []types.FileFormat) types.FileFormat {
+	var result types.FileFormat
+	if len(formats) == 0 {
+		return result
+	}
+
+	crlf, withBOM := 0, 0
+	modeCounts := make(map[fs.FileMode]int)
+	var modes []fs.FileMode
+	for _, format := range formats {
+		if format.CRLF {
+			crlf++
+		}
+		if format.BOM {
+			withBOM++
+		}
+		if format.Mode == 0 {
+			continue
+		}
+		if modeCounts[format.Mode] == 0 {
+			modes = append(modes, format.Mode)
+		}
+		modeCounts[format.Mode]++
+	}
+
+	result.CRLF = crlf*2 > len(formats)
+	result.BOM = withBOM*2 > len(formats)
+	for _, mode := range modes {
+		if modeCounts[mode] > modeCounts[result.Mode] {
+			result.Mode = mode
+		}
+	}
+	return result
+}
@@ -0,0 +1,66 @@
+package fileformat_test
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/tomoya-namekawa/tf-file-organize/internal/fileformat"
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/types"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		mode    fs.FileMode
+		want    types.FileFormat
+	}{
+		{"lf", "a\nb\n", 0644, types.FileFormat{Mode: 0644}},
+		{"crlf", "a\r\nb\r\n", 0600, types.FileFormat{CRLF: true, Mode: 0600}},
+		{"mostly crlf", "a\r\nb\r\nc\n", 0600, types.FileFormat{CRLF: true, Mode: 0600}},
+		{"bom", "\xEF\xBB\xBFa\n", 0600, types.FileFormat{BOM: true, Mode: 0600}},
+		{"mode bits only", "a\n", fs.ModeSetuid | 0755, types.FileFormat{Mode: 0755}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fileformat.Detect([]byte(tt.content), tt.mode); got != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDecodeEncodeRoundTrip(t *testing.T) {
+	original := "\xEF\xBB\xBFvariable \"a\" {}\r\n\r\nvariable \"b\" {}\r\n"
+	format := fileformat.Detect([]byte(original), 0)
+
+	decoded := fileformat.Decode([]byte(original))
+	if string(decoded) != "variable \"a\" {}\n\nvariable \"b\" {}\n" {
+		t.Errorf("Unexpected decoded content: %q", decoded)
+	}
+	if encoded := fileformat.Encode(decoded, format); string(encoded) != original {
+		t.Errorf("Expected round trip to %q, got %q", original, encoded)
+	}
+}
+
+func TestDominant(t *testing.T) {
+	got := fileformat.Dominant([]types.FileFormat{
+		{CRLF: true, Mode: 0644},
+		{CRLF: true, BOM: true, Mode: 0600},
+		{Mode: 0644},
+	})
+	want := types.FileFormat{CRLF: true, Mode: 0644}
+	if got != want {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+
+	// Ties favor LF without a byte order mark, and the first mode seen
+	got = fileformat.Dominant([]types.FileFormat{{CRLF: true, BOM: true, Mode: 0640}, {Mode: 0644}})
+	if want := (types.FileFormat{Mode: 0640}); got != want {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+
+	if got := fileformat.Dominant(nil); got != (types.FileFormat{}) {
+		t.Errorf("Expected zero format, got %+v", got)
+	}
+}
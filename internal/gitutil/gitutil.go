@@ -0,0 +1,156 @@
+// Package gitutil runs the git commands used by git-aware runs: checking the
+// worktree, moving files with history and staging the result.
+package gitutil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Repo is a git worktree, addressed through a directory inside it.
+type Repo struct {
+	dir string // absolute directory the git commands run in
+}
+
+// Open returns the repository containing dir. It fails if git is not
+// installed or dir is not inside a git worktree.
+func Open(dir string) (*Repo, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", dir, err)
+	}
+	r := &Repo{dir: abs}
+	if _, err := r.run("rev-parse", "--show-toplevel"); err != nil {
+		return nil, fmt.Errorf("%s is not inside a git worktree: %w", dir, err)
+	}
+	return r, nil
+}
+
+// Dirty returns the paths with uncommitted changes, including untracked
+// files, anywhere in the worktree.
+func (r *Repo) Dirty() ([]string, error) {
+	out, err := r.run("status", "--porcelain", "--untracked-files=all")
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, line := range strings.Split(out, "\n") {
+		if len(line) > 3 {
+			paths = append(paths, line[3:])
+		}
+	}
+	return paths, nil
+}
+
+// Tracked reports whether path is tracked by git.
+func (r *Repo) Tracked(path string) (bool, error) {
+	rel, err := r.rel(path)
+	if err != nil {
+		return false, err
+	}
+	out, err := r.run("ls-files", "--", rel)
+	if err != nil {
+		return false, err
+	}
+	return out != "", nil
+}
+
+// Move renames a tracked file with git mv, so git follows its history.
+func (r *Repo) Move(src, dst string) error {
+	relSrc, err := r.rel(src)
+	if err != nil {
+		return err
+	}
+	relDst, err := r.rel(dst)
+	if err != nil {
+		return err
+	}
+	_, err = r.run("mv", "--", relSrc, relDst)
+	return err
+}
+
+// Stage stages the current state of paths: changed and new files are added
+// and deleted files are removed from the index.
+func (r *Repo) Stage(paths []string, exists func(path string) bool) error {
+	var present, missing []string
+	for _, path := range paths {
+		rel, err := r.rel(path)
+		if err != nil {
+			return err
+		}
+		if exists(path) {
+			present = append(present, rel)
+		} else {
+			missing = append(missing, rel)
+		}
+	}
+	if len(present) > 0 {
+		if _, err := r.run(append([]string{"add", "--"}, present...)...); err != nil {
+			return err
+		}
+	}
+	if len(missing) > 0 {
+		if _, err := r.run(append([]string{"rm", "--cached", "--quiet", "--ignore-unmatch", "--"}, missing...)...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ChangedFiles returns the files under the directory of the repository that
+// differ from ref in the worktree, including deleted and untracked files, as
+// absolute paths.
+func (r *Repo) ChangedFiles(ref string) ([]string, error) {
+	if strings.HasPrefix(ref, "-") {
+		return nil, fmt.Errorf("invalid git ref %q", ref)
+	}
+	if _, err := r.run("rev-parse", "--verify", "--quiet", ref+"^{commit}"); err != nil {
+		return nil, fmt.Errorf("unknown git ref %q", ref)
+	}
+
+	diff, err := r.run("diff", "--name-only", "-z", "--relative", ref, "--", ".")
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := r.run("ls-files", "-z", "--others", "--exclude-standard", "--", ".")
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, name := range strings.Split(diff+"\x00"+untracked, "\x00") {
+		if name != "" {
+			paths = append(paths, filepath.Join(r.dir, filepath.FromSlash(name)))
+		}
+	}
+	return paths, nil
+}
+
+// rel converts path to a path relative to the directory git runs in.
+func (r *Repo) rel(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	return filepath.Rel(r.dir, abs)
+}
+
+func (r *Repo) run(args ...string) (string, error) {
+	cmd := exec.Command("git", args...) //nolint:gosec // arguments are git subcommands and paths
+	cmd.Dir = r.dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && stderr.Len() > 0 {
+			return "", fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(stderr.String()))
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimRight(stdout.String(), "\n"), nil
+}
@@ -0,0 +1,63 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// Buffer returns a logger that holds the events logged through it until
+// flush is called, which sends them to logger in the order they were logged.
+// It lets work running concurrently report in a deterministic order.
+func Buffer(logger *slog.Logger) (buffered *slog.Logger, flush func()) {
+	events := &bufferedEvents{}
+	return slog.New(&bufferHandler{next: logger.Handler(), events: events}), events.flush
+}
+
+// bufferedEvents holds events with the handler they are destined for.
+type bufferedEvents struct {
+	mu      sync.Mutex
+	pending []func()
+}
+
+func (e *bufferedEvents) add(event func()) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.pending = append(e.pending, event)
+}
+
+func (e *bufferedEvents) flush() {
+	e.mu.Lock()
+	pending := e.pending
+	e.pending = nil
+	e.mu.Unlock()
+	for _, event := range pending {
+		event()
+	}
+}
+
+// bufferHandler defers events to next until they are flushed.
+type bufferHandler struct {
+	next   slog.Handler
+	events *bufferedEvents
+}
+
+func (h *bufferHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *bufferHandler) Handle(_ context.Context, record slog.Record) error {
+	record = record.Clone()
+	h.events.add(func() {
+		_ = h.next.Handle(context.Background(), record)
+	})
+	return nil
+}
+
+func (h *bufferHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &bufferHandler{next: h.next.WithAttrs(attrs), events: h.events}
+}
+
+func (h *bufferHandler) WithGroup(name string) slog.Handler {
+	return &bufferHandler{next: h.next.WithGroup(name), events: h.events}
+}
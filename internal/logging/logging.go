@@ -0,0 +1,135 @@
+// Package logging builds the structured loggers that report progress and
+// warnings while organizing files.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Log formats accepted by New.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Options configures a logger built by New.
+type Options struct {
+	Level  slog.Level // Minimum level of the events written
+	Format string     // FormatText (default) or FormatJSON
+}
+
+// New returns a logger writing events of at least opts.Level to w. The text
+// format is meant for terminals: each event is one line holding the message
+// followed by its attributes, with warnings and errors prefixed.
+func New(w io.Writer, opts Options) (*slog.Logger, error) {
+	switch opts.Format {
+	case "", FormatText:
+		return slog.New(&consoleHandler{w: w, level: opts.Level, mu: &sync.Mutex{}}), nil
+	case FormatJSON:
+		return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: opts.Level})), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q (expected %q or %q)", opts.Format, FormatText, FormatJSON)
+	}
+}
+
+// Discard returns a logger that drops every event.
+func Discard() *slog.Logger {
+	return slog.New(slog.DiscardHandler)
+}
+
+// OrDiscard returns logger, or a logger that drops every event if it is nil.
+func OrDiscard(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return Discard()
+	}
+	return logger
+}
+
+// consoleHandler writes events as "Warning: message key=value" lines without
+// timestamps or levels for informational events.
+type consoleHandler struct {
+	w      io.Writer
+	level  slog.Level
+	mu     *sync.Mutex
+	attrs  string // preformatted attributes added with WithAttrs
+	prefix string // group prefix for attribute keys
+}
+
+func (h *consoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *consoleHandler) Handle(_ context.Context, record slog.Record) error {
+	var b strings.Builder
+	switch {
+	case record.Level >= slog.LevelError:
+		b.WriteString("Error: ")
+	case record.Level >= slog.LevelWarn:
+		b.WriteString("Warning: ")
+	}
+	b.WriteString(record.Message)
+	b.WriteString(h.attrs)
+	record.Attrs(func(attr slog.Attr) bool {
+		appendAttr(&b, h.prefix, attr)
+		return true
+	})
+	b.WriteString("\n")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+func (h *consoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var b strings.Builder
+	for _, attr := range attrs {
+		appendAttr(&b, h.prefix, attr)
+	}
+	clone := *h
+	clone.attrs += b.String()
+	return &clone
+}
+
+func (h *consoleHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.prefix += name + "."
+	return &clone
+}
+
+// appendAttr writes attr as " key=value", quoting values that contain spaces
+// or quotes. Group attributes are flattened with dotted keys.
+func appendAttr(b *strings.Builder, prefix string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+		for _, member := range attr.Value.Group() {
+			appendAttr(b, prefix, member)
+		}
+		return
+	}
+
+	value := attr.Value.String()
+	if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+		value = strconv.Quote(value)
+	}
+	b.WriteString(" ")
+	b.WriteString(prefix)
+	b.WriteString(attr.Key)
+	b.WriteString("=")
+	b.WriteString(value)
+}
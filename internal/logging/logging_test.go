@@ -0,0 +1,76 @@
+package logging_test
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/tomoya-namekawa/tf-file-organize/internal/logging"
+)
+
+func TestNewText(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, logging.Options{Level: slog.LevelInfo})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	logger.Debug("Parsed file", "path", "main.tf")
+	logger.Info("Created file", "path", "variables.tf")
+	logger.With("module", "network").Warn("failed to parse file", "path", "my file.tf", "error", errors.New("bad"))
+	logger.WithGroup("stats").Error("failed", slog.Int("files", 2))
+
+	expected := `Created file path=variables.tf
+Warning: failed to parse file module=network path="my file.tf" error=bad
+Error: failed stats.files=2
+`
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestNewFormat(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, logging.Options{Format: logging.FormatJSON, Level: slog.LevelDebug})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	logger.Debug("Parsed file", "path", "main.tf")
+	if !bytes.Contains(buf.Bytes(), []byte(`"level":"DEBUG","msg":"Parsed file","path":"main.tf"`)) {
+		t.Errorf("Unexpected JSON output %s", buf.String())
+	}
+
+	if _, err := logging.New(&buf, logging.Options{Format: "xml"}); err == nil {
+		t.Error("Expected error for unknown log format")
+	}
+}
+
+func TestBuffer(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, logging.Options{Level: slog.LevelInfo})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	buffered, flush := logging.Buffer(logger)
+	buffered.Info("Parsing module", "path", "network")
+	buffered.Debug("Parsed file", "path", "main.tf")
+	buffered.With("module", "network").Warn("failed to parse file", "path", "bad.tf")
+	logger.Info("Created file", "path", "variables.tf")
+
+	flush()
+	expected := `Created file path=variables.tf
+Parsing module path=network
+Warning: failed to parse file module=network path=bad.tf
+`
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+
+	buf.Reset()
+	flush()
+	if buf.Len() != 0 {
+		t.Errorf("Expected flushed events to be reported once, got:\n%s", buf.String())
+	}
+}
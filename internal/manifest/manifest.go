@@ -0,0 +1,134 @@
+// Package manifest records the files generated in an output directory so that
+// files which are no longer produced can be pruned on the next run.
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/filesystem"
+)
+
+// FileName is the manifest file written to each output directory.
+const FileName = ".tf-file-organize.lock.json"
+
+const currentVersion = 1
+
+// Manifest maps generated file names to what was written to them.
+type Manifest struct {
+	Version int                  `json:"version"`
+	Files   map[string]FileEntry `json:"files"`
+}
+
+// FileEntry records the content hash of a generated file and the addresses of
+// the blocks the tool wrote to it. Blocks without labels share their address,
+// so their content hashes are recorded as well.
+type FileEntry struct {
+	Hash      string   `json:"hash"`
+	Blocks    []string `json:"blocks"`
+	Unlabeled []string `json:"unlabeled,omitempty"`
+}
+
+// New returns an empty manifest.
+func New() *Manifest {
+	return &Manifest{Version: currentVersion, Files: make(map[string]FileEntry)}
+}
+
+// Load reads the manifest in dir. A missing manifest yields an empty one.
+func Load(fsys filesystem.FileSystem, dir string) (*Manifest, error) {
+	path := filepath.Join(dir, FileName)
+	data, err := fsys.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return New(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest %s: %w", path, err)
+	}
+
+	m := New()
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+	if m.Version > currentVersion {
+		return nil, fmt.Errorf("manifest %s has unsupported version %d", path, m.Version)
+	}
+	if m.Files == nil {
+		m.Files = make(map[string]FileEntry)
+	}
+	return m, nil
+}
+
+// Save writes the manifest to dir.
+func (m *Manifest) Save(fsys filesystem.FileSystem, dir string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	path := filepath.Join(dir, FileName)
+	if err := fsys.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write manifest %s: %w", path, err)
+	}
+	return nil
+}
+
+// Record stores the hash of a generated file's content and the addresses of
+// the blocks written to it.
+func (m *Manifest) Record(fileName string, content []byte, blocks []string) {
+	sorted := slices.Clone(blocks)
+	sort.Strings(sorted)
+	m.Files[fileName] = FileEntry{Hash: Hash(content), Blocks: slices.Compact(sorted)}
+}
+
+// RecordUnlabeled stores the content hashes of the blocks without labels
+// written to fileName, which must have been recorded.
+func (m *Manifest) RecordUnlabeled(fileName string, hashes []string) {
+	entry := m.Files[fileName]
+	sorted := slices.Clone(hashes)
+	sort.Strings(sorted)
+	entry.Unlabeled = slices.Compact(sorted)
+	m.Files[fileName] = entry
+}
+
+// Wrote reports whether a block with the given address was written to fileName.
+func (m *Manifest) Wrote(fileName, address string) bool {
+	_, found := slices.BinarySearch(m.Files[fileName].Blocks, address)
+	return found
+}
+
+// WroteUnlabeled reports whether a block without labels with the given
+// content hash was written to fileName.
+func (m *Manifest) WroteUnlabeled(fileName, hash string) bool {
+	_, found := slices.BinarySearch(m.Files[fileName].Unlabeled, hash)
+	return found
+}
+
+// Stale returns the recorded files that are not in current, sorted by name.
+func (m *Manifest) Stale(current map[string]bool) []string {
+	var stale []string
+	for fileName := range m.Files {
+		if !current[fileName] {
+			stale = append(stale, fileName)
+		}
+	}
+	sort.Strings(stale)
+	return stale
+}
+
+// Unchanged reports whether content is what was recorded for fileName.
+func (m *Manifest) Unchanged(fileName string, content []byte) bool {
+	entry, exists := m.Files[fileName]
+	return exists && entry.Hash == Hash(content)
+}
+
+// Hash returns the content hash stored in the manifest.
+func Hash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
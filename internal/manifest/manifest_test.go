@@ -0,0 +1,80 @@
+package manifest_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/tomoya-namekawa/tf-file-organize/internal/manifest"
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/filesystem"
+)
+
+func TestLoadMissing(t *testing.T) {
+	m, err := manifest.Load(filesystem.OS(), t.TempDir())
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(m.Files) != 0 {
+		t.Errorf("Expected empty manifest, got %v", m.Files)
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	m := manifest.New()
+	m.Record("network.tf", []byte("resource \"aws_vpc\" \"main\" {}\n"), []string{"resource.aws_vpc.main"})
+	m.Record("variables.tf", []byte("variable \"region\" {}\n"), []string{"variable.region"})
+	if err := m.Save(filesystem.OS(), dir); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, manifest.FileName)); err != nil {
+		t.Fatalf("Expected manifest file: %v", err)
+	}
+
+	loaded, err := manifest.Load(filesystem.OS(), dir)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Files, m.Files) {
+		t.Errorf("Expected %v, got %v", m.Files, loaded.Files)
+	}
+}
+
+func TestStaleAndUnchanged(t *testing.T) {
+	m := manifest.New()
+	m.Record("network.tf", []byte("a"), nil)
+	m.Record("compute.tf", []byte("b"), nil)
+	m.Record("variables.tf", []byte("c"), nil)
+
+	stale := m.Stale(map[string]bool{"variables.tf": true})
+	if !reflect.DeepEqual(stale, []string{"compute.tf", "network.tf"}) {
+		t.Errorf("Unexpected stale files: %v", stale)
+	}
+
+	if !m.Unchanged("network.tf", []byte("a")) {
+		t.Error("Expected recorded content to be unchanged")
+	}
+	if m.Unchanged("network.tf", []byte("edited")) {
+		t.Error("Expected edited content to be reported as changed")
+	}
+	if m.Unchanged("unknown.tf", []byte("a")) {
+		t.Error("Expected unrecorded file to be reported as changed")
+	}
+}
+
+func TestWrote(t *testing.T) {
+	m := manifest.New()
+	m.Record("network.tf", []byte("a"), []string{"resource.aws_vpc.main", "resource.aws_subnet.a", "resource.aws_vpc.main"})
+
+	if got := m.Files["network.tf"].Blocks; !reflect.DeepEqual(got, []string{"resource.aws_subnet.a", "resource.aws_vpc.main"}) {
+		t.Errorf("Expected sorted unique blocks, got %v", got)
+	}
+	if !m.Wrote("network.tf", "resource.aws_vpc.main") {
+		t.Error("Expected recorded block to be reported as written")
+	}
+	if m.Wrote("network.tf", "resource.aws_instance.web") || m.Wrote("compute.tf", "resource.aws_vpc.main") {
+		t.Error("Expected unrecorded blocks to be reported as not written")
+	}
+}
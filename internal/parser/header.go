@@ -0,0 +1,57 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/types"
+)
+
+// fileHeader returns the first comment block of content if it is followed by
+// a blank line before the first block, which starts at offset blockStart.
+func fileHeader(content []byte, blockStart int) string {
+	if blockStart > len(content) {
+		return ""
+	}
+	lines := strings.Split(string(content[:blockStart]), "\n")
+
+	start := 0
+	for start < len(lines) && strings.TrimSpace(lines[start]) == "" {
+		start++
+	}
+	var header []string
+	end := start
+	for ; end < len(lines); end++ {
+		line := strings.TrimSpace(lines[end])
+		if !strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "//") {
+			break
+		}
+		header = append(header, line)
+	}
+	// The last line holds the indentation before the block keyword
+	if len(header) == 0 || end >= len(lines)-1 || strings.TrimSpace(lines[end]) != "" {
+		return ""
+	}
+	return strings.Join(header, "\n")
+}
+
+// SplitFileHeader detaches the file-level header of a parsed file, such as a
+// license banner, from the leading comments of its first block and returns it.
+// When pattern is set, the header must match it. It returns "" when the file
+// has no such header.
+func SplitFileHeader(file *types.ParsedFile, pattern *regexp.Regexp) string {
+	if file.Header == "" || len(file.Blocks) == 0 {
+		return ""
+	}
+	if pattern != nil && !pattern.MatchString(file.Header) {
+		return ""
+	}
+
+	first := file.Blocks[0]
+	rest, found := strings.CutPrefix(strings.TrimLeft(first.LeadingComments, "\n"), file.Header)
+	if !found {
+		return ""
+	}
+	first.LeadingComments = strings.TrimLeft(rest, "\n")
+	return file.Header
+}
@@ -0,0 +1,65 @@
+package parser_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/tomoya-namekawa/tf-file-organize/internal/parser"
+)
+
+func TestSplitFileHeader(t *testing.T) {
+	tests := []struct {
+		name         string
+		content      string
+		pattern      *regexp.Regexp
+		wantHeader   string
+		wantComments string
+	}{
+		{
+			name:         "header followed by blank line",
+			content:      "# Copyright 2024 Example Corp\n# SPDX-License-Identifier: MPL-2.0\n\n# VPC\nresource \"aws_vpc\" \"main\" {}\n",
+			wantHeader:   "# Copyright 2024 Example Corp\n# SPDX-License-Identifier: MPL-2.0",
+			wantComments: "# VPC",
+		},
+		{
+			name:         "comment attached to block",
+			content:      "# VPC\nresource \"aws_vpc\" \"main\" {}\n",
+			wantComments: "# VPC",
+		},
+		{
+			name:         "pattern does not match",
+			content:      "# Network resources\n\nresource \"aws_vpc\" \"main\" {}\n",
+			pattern:      regexp.MustCompile(`(?i)copyright`),
+			wantComments: "# Network resources",
+		},
+		{
+			name:       "pattern matches",
+			content:    "// Copyright Example Corp\n\nresource \"aws_vpc\" \"main\" {}\n",
+			pattern:    regexp.MustCompile(`(?i)copyright`),
+			wantHeader: "// Copyright Example Corp",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tfPath := filepath.Join(t.TempDir(), "main.tf")
+			if err := os.WriteFile(tfPath, []byte(tt.content), 0600); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+			parsed, err := parser.New().ParseFile(context.Background(), tfPath)
+			if err != nil {
+				t.Fatalf("ParseFile failed: %v", err)
+			}
+
+			if header := parser.SplitFileHeader(parsed, tt.pattern); header != tt.wantHeader {
+				t.Errorf("Expected header %q, got %q", tt.wantHeader, header)
+			}
+			if comments := parsed.Blocks[0].LeadingComments; comments != tt.wantComments {
+				t.Errorf("Expected leading comments %q, got %q", tt.wantComments, comments)
+			}
+		})
+	}
+}
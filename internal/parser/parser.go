@@ -2,35 +2,80 @@
 package parser
 
 import (
+	"context"
 	"fmt"
-	"os"
 	"strings"
 
 	"github.com/hashicorp/hcl/v2"
-	"github.com/hashicorp/hcl/v2/hclparse"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
 
+	"github.com/tomoya-namekawa/tf-file-organize/internal/fileformat"
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/filesystem"
 	"github.com/tomoya-namekawa/tf-file-organize/pkg/types"
 )
 
+// blockSchema lists the top-level blocks that are organized.
+var blockSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "terraform"},
+		{Type: "provider", LabelNames: []string{"name"}},
+		{Type: "variable", LabelNames: []string{"name"}},
+		{Type: "locals"},
+		{Type: "data", LabelNames: []string{"type", "name"}},
+		{Type: "resource", LabelNames: []string{"type", "name"}},
+		{Type: "module", LabelNames: []string{"name"}},
+		{Type: "output", LabelNames: []string{"name"}},
+	},
+}
+
 type Parser struct {
-	parser *hclparse.Parser
+	fsys filesystem.FileSystem
 }
 
 func New() *Parser {
+	return NewWithFS(filesystem.OS())
+}
+
+// NewWithFS creates a Parser reading files from fsys.
+func NewWithFS(fsys filesystem.FileSystem) *Parser {
 	return &Parser{
-		parser: hclparse.NewParser(),
+		fsys: fsys,
 	}
 }
 
-// ParseFile parses a Terraform file with comment preservation
-func (p *Parser) ParseFile(filename string) (*types.ParsedFile, error) {
-	content, err := os.ReadFile(filename) //nolint:gosec // filename is validated before use
+// ParseFile parses a Terraform file with comment preservation. It fails
+// without reading the file if ctx is already canceled.
+func (p *Parser) ParseFile(ctx context.Context, filename string) (*types.ParsedFile, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	content, err := p.fsys.ReadFile(filename)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file %s: %w", filename, err)
 	}
 
-	file, diags := p.parser.ParseHCL(content, filename)
+	stat, err := p.fsys.Stat(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file %s: %w", filename, err)
+	}
+
+	parsedFile, err := p.Parse(filename, content)
+	if err != nil {
+		return nil, err
+	}
+	parsedFile.Format.Mode = stat.Mode().Perm()
+	return parsedFile, nil
+}
+
+// Parse parses Terraform content with comment preservation. The filename is
+// only used to name the file and its blocks; content need not come from a
+// file, for example when it is read from stdin.
+func (p *Parser) Parse(filename string, content []byte) (*types.ParsedFile, error) {
+	format := fileformat.Detect(content, 0)
+	content = fileformat.Decode(content)
+
+	file, diags := hclsyntax.ParseConfig(content, filename, hcl.InitialPos)
 	if diags.HasErrors() {
 		return nil, fmt.Errorf("failed to parse HCL: %s", diags.Error())
 	}
@@ -38,66 +83,48 @@ func (p *Parser) ParseFile(filename string) (*types.ParsedFile, error) {
 	parsedFile := &types.ParsedFile{
 		FileName: filename,
 		Blocks:   make([]*types.Block, 0),
+		Format:   format,
 	}
 
-	if file.Body == nil {
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
 		return parsedFile, nil
 	}
 
-	schema := &hcl.BodySchema{
-		Blocks: []hcl.BlockHeaderSchema{
-			{Type: "terraform"},
-			{Type: "provider", LabelNames: []string{"name"}},
-			{Type: "variable", LabelNames: []string{"name"}},
-			{Type: "locals"},
-			{Type: "data", LabelNames: []string{"type", "name"}},
-			{Type: "resource", LabelNames: []string{"type", "name"}},
-			{Type: "module", LabelNames: []string{"name"}},
-			{Type: "output", LabelNames: []string{"name"}},
-		},
-	}
-
-	content_hcl, _, diags := file.Body.PartialContent(schema)
+	content_hcl, _, diags := body.PartialContent(blockSchema)
 	if diags.HasErrors() {
 		return nil, fmt.Errorf("failed to extract content: %s", diags.Error())
 	}
 
-	syntaxFile, syntaxDiags := hclsyntax.ParseConfig(content, filename, hcl.InitialPos)
-	if syntaxDiags.HasErrors() {
-		for _, block := range content_hcl.Blocks {
-			parsedBlock := &types.Block{
-				Type:            block.Type,
-				Labels:          block.Labels,
-				Body:            block.Body,
-				DefRange:        block.DefRange,
-				TypeRange:       block.TypeRange,
-				RawBody:         "",
-				LeadingComments: "",
-				SourceFile:      filename,
-			}
-			parsedFile.Blocks = append(parsedFile.Blocks, parsedBlock)
+	// The syntax tree parsed above also provides the raw bodies and comments.
+	// PartialContent skips blocks outside the schema, so blocks are matched
+	// with their syntax blocks by position rather than by index.
+	syntaxIndex := make(map[int]int, len(body.Blocks))
+	for i, syntaxBlock := range body.Blocks {
+		syntaxIndex[syntaxBlock.TypeRange.Start.Byte] = i
+	}
+	if len(body.Blocks) > 0 {
+		parsedFile.Header = fileHeader(content, body.Blocks[0].TypeRange.Start.Byte)
+	}
+
+	for _, block := range content_hcl.Blocks {
+		var rawBody, leadingComments string
+		if i, ok := syntaxIndex[block.TypeRange.Start.Byte]; ok {
+			rawBody = p.extractRawBodyFromSyntax(content, body.Blocks[i])
+			leadingComments = p.extractLeadingComments(content, body.Blocks[i], i, body.Blocks)
 		}
-	} else {
-		for i, block := range content_hcl.Blocks {
-			var rawBody, leadingComments string
-			if i < len(syntaxFile.Body.(*hclsyntax.Body).Blocks) {
-				syntaxBlock := syntaxFile.Body.(*hclsyntax.Body).Blocks[i]
-				rawBody = p.extractRawBodyFromSyntax(content, syntaxBlock)
-				leadingComments = p.extractLeadingComments(content, syntaxBlock, i, syntaxFile.Body.(*hclsyntax.Body).Blocks)
-			}
 
-			parsedBlock := &types.Block{
-				Type:            block.Type,
-				Labels:          block.Labels,
-				Body:            block.Body,
-				DefRange:        block.DefRange,
-				TypeRange:       block.TypeRange,
-				RawBody:         rawBody,
-				LeadingComments: leadingComments,
-				SourceFile:      filename,
-			}
-			parsedFile.Blocks = append(parsedFile.Blocks, parsedBlock)
+		parsedBlock := &types.Block{
+			Type:            block.Type,
+			Labels:          block.Labels,
+			Body:            block.Body,
+			DefRange:        block.DefRange,
+			TypeRange:       block.TypeRange,
+			RawBody:         rawBody,
+			LeadingComments: leadingComments,
+			SourceFile:      filename,
 		}
+		parsedFile.Blocks = append(parsedFile.Blocks, parsedBlock)
 	}
 
 	return parsedFile, nil
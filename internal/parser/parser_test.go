@@ -1,6 +1,7 @@
 package parser_test
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
@@ -65,7 +66,7 @@ output "instance_id" {
 	}
 
 	p := parser.New()
-	parsedFile, err := p.ParseFile(tfPath)
+	parsedFile, err := p.ParseFile(context.Background(), tfPath)
 	if err != nil {
 		t.Fatalf("ParseFile failed: %v", err)
 	}
@@ -124,7 +125,7 @@ output "instance_id" {
 
 func TestParseFileNonExistent(t *testing.T) {
 	p := parser.New()
-	_, err := p.ParseFile("/nonexistent/file.tf")
+	_, err := p.ParseFile(context.Background(), "/nonexistent/file.tf")
 	if err == nil {
 		t.Error("Expected error for nonexistent file, got nil")
 	}
@@ -146,7 +147,7 @@ resource "aws_instance" "web" {
 	}
 
 	p := parser.New()
-	_, err = p.ParseFile(tfPath)
+	_, err = p.ParseFile(context.Background(), tfPath)
 	if err == nil {
 		t.Error("Expected error for invalid HCL, got nil")
 	}
@@ -162,7 +163,7 @@ func TestParseFileEmpty(t *testing.T) {
 	}
 
 	p := parser.New()
-	parsedFile, err := p.ParseFile(tfPath)
+	parsedFile, err := p.ParseFile(context.Background(), tfPath)
 	if err != nil {
 		t.Fatalf("ParseFile failed for empty file: %v", err)
 	}
@@ -209,7 +210,7 @@ resource "aws_security_group" "web" {
 	}
 
 	p := parser.New()
-	parsedFile, err := p.ParseFile(tfPath)
+	parsedFile, err := p.ParseFile(context.Background(), tfPath)
 	if err != nil {
 		t.Fatalf("ParseFile failed for complex resource: %v", err)
 	}
@@ -227,3 +228,103 @@ resource "aws_security_group" "web" {
 		t.Errorf("Expected labels [aws_security_group, web], got %v", block.Labels)
 	}
 }
+
+func TestParseFileFormat(t *testing.T) {
+	tmpDir := t.TempDir()
+	tfPath := filepath.Join(tmpDir, "windows.tf")
+
+	content := "\xEF\xBB\xBF# Network\r\nresource \"aws_vpc\" \"main\" {\r\n  cidr_block = \"10.0.0.0/16\"\r\n}\r\n"
+	if err := os.WriteFile(tfPath, []byte(content), 0640); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	p := parser.New()
+	parsed, err := p.ParseFile(context.Background(), tfPath)
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+
+	want := types.FileFormat{CRLF: true, BOM: true, Mode: 0640}
+	if parsed.Format != want {
+		t.Errorf("Expected format %+v, got %+v", want, parsed.Format)
+	}
+	if len(parsed.Blocks) != 1 {
+		t.Fatalf("Expected 1 block, got %d", len(parsed.Blocks))
+	}
+	block := parsed.Blocks[0]
+	if block.LeadingComments != "# Network" {
+		t.Errorf("Expected leading comment without byte order mark, got %q", block.LeadingComments)
+	}
+	if block.RawBody != "\n  cidr_block = \"10.0.0.0/16\"\n" {
+		t.Errorf("Expected raw body with \\n line endings, got %q", block.RawBody)
+	}
+}
+
+func TestParse(t *testing.T) {
+	p := parser.New()
+
+	first, err := p.Parse("stdin.tf", []byte("# Region\nvariable \"region\" {\n  type = string\n}\n"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if first.FileName != "stdin.tf" || len(first.Blocks) != 1 || first.Blocks[0].SourceFile != "stdin.tf" {
+		t.Errorf("Unexpected parsed file %+v", first)
+	}
+	if first.Blocks[0].LeadingComments != "# Region" {
+		t.Errorf("Expected leading comment, got %q", first.Blocks[0].LeadingComments)
+	}
+
+	// Content parsed again under the same name is not cached
+	second, err := p.Parse("stdin.tf", []byte("output \"id\" {\n  value = 1\n}\n"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(second.Blocks) != 1 || second.Blocks[0].Type != "output" {
+		t.Errorf("Expected the new content to be parsed, got %+v", second.Blocks)
+	}
+
+	if _, err := p.Parse("stdin.tf", []byte("variable {")); err == nil {
+		t.Error("Expected error for invalid HCL")
+	}
+}
+
+func TestParseSkipsUnknownBlocks(t *testing.T) {
+	content := `moved {
+  from = aws_vpc.old
+  to   = aws_vpc.main
+}
+
+# Main network
+resource "aws_vpc" "main" {
+  cidr_block = "10.0.0.0/16"
+}
+`
+	parsed, err := parser.New().Parse("main.tf", []byte(content))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(parsed.Blocks) != 1 {
+		t.Fatalf("Expected 1 block, got %d", len(parsed.Blocks))
+	}
+	block := parsed.Blocks[0]
+	if block.RawBody != "\n  cidr_block = \"10.0.0.0/16\"\n" {
+		t.Errorf("Expected the body of the resource, got %q", block.RawBody)
+	}
+	if block.LeadingComments != "\n\n# Main network" {
+		t.Errorf("Expected the comment of the resource, got %q", block.LeadingComments)
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	content, err := os.ReadFile(filepath.Join("..", "..", "testdata", "terraform", "sample.tf"))
+	if err != nil {
+		b.Fatalf("Failed to read sample: %v", err)
+	}
+	p := parser.New()
+	b.SetBytes(int64(len(content)))
+	for b.Loop() {
+		if _, err := p.Parse("sample.tf", content); err != nil {
+			b.Fatalf("Parse failed: %v", err)
+		}
+	}
+}
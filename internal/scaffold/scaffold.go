@@ -0,0 +1,304 @@
+// Package scaffold infers a starter configuration from an existing file layout.
+package scaffold
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tomoya-namekawa/tf-file-organize/internal/config"
+	"github.com/tomoya-namekawa/tf-file-organize/internal/splitter"
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/types"
+)
+
+// placement records where a block currently lives and where it would go by default.
+type placement struct {
+	block       *types.Block
+	currentFile string
+	defaultFile string
+}
+
+// typeInfo collects the placements of all blocks sharing a type key such as
+// "resource.aws_instance", "data.aws_ami", "variable" or "locals".
+type typeInfo struct {
+	key        string
+	blockType  string
+	subType    string
+	placements []placement
+}
+
+// InferConfig builds a configuration whose groups reproduce the current
+// placement of blocks as closely as possible. Blocks already in their default
+// file need no pattern; types living in a single file get a type pattern,
+// collapsed into wildcards where that cannot capture types placed elsewhere;
+// types split across files get one pattern per block.
+func InferConfig(parsedFiles *types.ParsedFiles) (*config.Config, error) {
+	defaults, err := defaultFiles(parsedFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := collectTypes(parsedFiles, defaults)
+
+	typePatterns := make(map[string][]*typeInfo) // file -> types with a type pattern
+	blockPatterns := make(map[string][]string)   // file -> per-block patterns
+	wholeFile := make(map[string]string)         // type key -> file holding every block of that type
+
+	for _, info := range infos {
+		if info.atDefault("") {
+			continue
+		}
+
+		file := info.majorityFile()
+		if len(info.files()) == 1 || !info.hasLabels() {
+			if info.atDefault(file) {
+				continue
+			}
+			wholeFile[info.key] = file
+			typePatterns[file] = append(typePatterns[file], info)
+			continue
+		}
+
+		// Split across files: the majority file keeps a type pattern unless it is
+		// the default anyway, and every other block gets its own pattern.
+		typeInMajority := !info.atDefault(file)
+		if typeInMajority {
+			typePatterns[file] = append(typePatterns[file], info)
+		}
+		for _, p := range info.placements {
+			if typeInMajority && p.currentFile == file {
+				continue
+			}
+			if !typeInMajority && p.currentFile == p.defaultFile {
+				continue
+			}
+			blockPatterns[p.currentFile] = append(blockPatterns[p.currentFile], splitter.BlockAddress(p.block))
+		}
+	}
+
+	fileNames := make(map[string]bool)
+	for file := range typePatterns {
+		fileNames[file] = true
+	}
+	for file := range blockPatterns {
+		fileNames[file] = true
+	}
+	sortedFiles := make([]string, 0, len(fileNames))
+	for file := range fileNames {
+		sortedFiles = append(sortedFiles, file)
+	}
+	sort.Strings(sortedFiles)
+
+	cfg := &config.Config{}
+	usedNames := make(map[string]bool)
+	for _, file := range sortedFiles {
+		patterns := collapsePatterns(typePatterns[file], infos, wholeFile, file)
+		blocks := blockPatterns[file]
+		sort.Strings(blocks)
+		patterns = append(patterns, blocks...)
+
+		cfg.Groups = append(cfg.Groups, config.GroupConfig{
+			Name:     groupName(file, usedNames),
+			Filename: file,
+			Patterns: patterns,
+		})
+	}
+
+	return cfg, nil
+}
+
+// CountMoves returns how many blocks would change file if cfg were applied.
+func CountMoves(parsedFiles *types.ParsedFiles, cfg *config.Config) (int, error) {
+	groups, err := splitter.NewWithConfig(cfg).GroupBlocks(parsedFiles)
+	if err != nil {
+		return 0, err
+	}
+
+	moves := 0
+	for _, group := range groups {
+		for _, block := range group.Blocks {
+			if filepath.Base(block.SourceFile) != group.FileName {
+				moves++
+			}
+		}
+	}
+	return moves, nil
+}
+
+func defaultFiles(parsedFiles *types.ParsedFiles) (map[*types.Block]string, error) {
+	groups, err := splitter.New().GroupBlocks(parsedFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	defaults := make(map[*types.Block]string)
+	for _, group := range groups {
+		for _, block := range group.Blocks {
+			defaults[block] = group.FileName
+		}
+	}
+	return defaults, nil
+}
+
+func collectTypes(parsedFiles *types.ParsedFiles, defaults map[*types.Block]string) []*typeInfo {
+	byKey := make(map[string]*typeInfo)
+	var keys []string
+
+	for _, block := range parsedFiles.AllBlocks() {
+		key := block.Type
+		var subType string
+		if (block.Type == "resource" || block.Type == "data") && len(block.Labels) > 0 {
+			subType = block.Labels[0]
+			key = block.Type + "." + subType
+		}
+
+		info, exists := byKey[key]
+		if !exists {
+			info = &typeInfo{key: key, blockType: block.Type, subType: subType}
+			byKey[key] = info
+			keys = append(keys, key)
+		}
+		info.placements = append(info.placements, placement{
+			block:       block,
+			currentFile: filepath.Base(block.SourceFile),
+			defaultFile: defaults[block],
+		})
+	}
+
+	sort.Strings(keys)
+	infos := make([]*typeInfo, 0, len(keys))
+	for _, key := range keys {
+		infos = append(infos, byKey[key])
+	}
+	return infos
+}
+
+func (t *typeInfo) files() map[string]int {
+	files := make(map[string]int)
+	for _, p := range t.placements {
+		files[p.currentFile]++
+	}
+	return files
+}
+
+// hasLabels reports whether individual blocks of this type can be addressed.
+func (t *typeInfo) hasLabels() bool {
+	return len(t.placements[0].block.Labels) > 0
+}
+
+// atDefault reports whether every block (in file, or anywhere if file is
+// empty) already sits in its default file.
+func (t *typeInfo) atDefault(file string) bool {
+	for _, p := range t.placements {
+		if (file == "" || p.currentFile == file) && p.currentFile != p.defaultFile {
+			return false
+		}
+	}
+	return true
+}
+
+// majorityFile returns the file holding most blocks of this type (ties by name).
+func (t *typeInfo) majorityFile() string {
+	var best string
+	bestCount := 0
+	for file, count := range t.files() {
+		if count > bestCount || (count == bestCount && file < best) {
+			best, bestCount = file, count
+		}
+	}
+	return best
+}
+
+// collapsePatterns turns the type keys placed wholly in file into patterns,
+// replacing families of resource or data types with a prefix wildcard when
+// every observed type matching the wildcard lives in the same file.
+func collapsePatterns(local []*typeInfo, all []*typeInfo, wholeFile map[string]string, file string) []string {
+	var patterns []string
+	covered := make(map[string]bool)
+
+	for _, info := range local {
+		if covered[info.key] {
+			continue
+		}
+		if info.blockType == "resource" || info.blockType == "data" {
+			if prefix, members := widestPrefix(info, local, all, wholeFile, file); len(members) > 1 {
+				patterns = append(patterns, info.blockType+"."+prefix+"*")
+				for _, member := range members {
+					covered[member] = true
+				}
+				continue
+			}
+		}
+		patterns = append(patterns, info.key)
+		covered[info.key] = true
+	}
+
+	return patterns
+}
+
+// widestPrefix finds the shortest underscore-delimited prefix of info's type
+// that matches at least one other local type and no type placed elsewhere.
+func widestPrefix(info *typeInfo, local, all []*typeInfo, wholeFile map[string]string, file string) (prefix string, members []string) {
+	sub := info.subType
+	first := strings.Index(sub, "_")
+	if first == -1 {
+		return "", nil
+	}
+
+	for i := first + 1; i < len(sub); i++ {
+		if sub[i-1] != '_' {
+			continue
+		}
+		candidate := sub[:i]
+		if !prefixSafe(candidate, info.blockType, all, wholeFile, file) {
+			continue
+		}
+
+		var matched []string
+		for _, other := range local {
+			if other.blockType == info.blockType && strings.HasPrefix(other.subType, candidate) {
+				matched = append(matched, other.key)
+			}
+		}
+		if len(matched) > 1 {
+			return candidate, matched
+		}
+		return "", nil
+	}
+	return "", nil
+}
+
+func prefixSafe(prefix, blockType string, all []*typeInfo, wholeFile map[string]string, file string) bool {
+	for _, other := range all {
+		if other.blockType != blockType || !strings.HasPrefix(other.subType, prefix) {
+			continue
+		}
+		if wholeFile[other.key] != file {
+			return false
+		}
+	}
+	return true
+}
+
+func groupName(file string, used map[string]bool) string {
+	base := strings.TrimSuffix(file, filepath.Ext(file))
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, base)
+	if name == "" {
+		name = "group"
+	}
+
+	unique := name
+	for i := 2; used[unique]; i++ {
+		unique = fmt.Sprintf("%s_%d", name, i)
+	}
+	used[unique] = true
+	return unique
+}
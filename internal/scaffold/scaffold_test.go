@@ -0,0 +1,100 @@
+package scaffold_test
+
+import (
+	"testing"
+
+	"github.com/tomoya-namekawa/tf-file-organize/internal/scaffold"
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/types"
+)
+
+func createTestFile(name string, blocks ...*types.Block) *types.ParsedFile {
+	for _, block := range blocks {
+		block.SourceFile = "/work/" + name
+	}
+	return &types.ParsedFile{FileName: "/work/" + name, Blocks: blocks}
+}
+
+func createTestBlock(blockType string, labels ...string) *types.Block {
+	return &types.Block{Type: blockType, Labels: labels}
+}
+
+func TestInferConfig(t *testing.T) {
+	parsedFiles := &types.ParsedFiles{
+		Files: []*types.ParsedFile{
+			createTestFile("network.tf",
+				createTestBlock("resource", "aws_vpc", "main"),
+				createTestBlock("resource", "aws_security_group", "web"),
+				createTestBlock("resource", "aws_security_group_rule", "web"),
+			),
+			createTestFile("compute.tf",
+				createTestBlock("resource", "aws_instance", "web"),
+				createTestBlock("resource", "aws_instance", "api"),
+			),
+			createTestFile("bastion.tf",
+				createTestBlock("resource", "aws_instance", "bastion"),
+			),
+			createTestFile("variables.tf",
+				createTestBlock("variable", "region"),
+			),
+			createTestFile("resource__aws_s3_bucket.tf",
+				createTestBlock("resource", "aws_s3_bucket", "logs"),
+			),
+		},
+	}
+
+	cfg, err := scaffold.InferConfig(parsedFiles)
+	if err != nil {
+		t.Fatalf("InferConfig failed: %v", err)
+	}
+
+	expected := map[string][]string{
+		"bastion.tf": {"resource.aws_instance.bastion"},
+		"compute.tf": {"resource.aws_instance"},
+		"network.tf": {"resource.aws_security_*", "resource.aws_vpc"},
+	}
+	if len(cfg.Groups) != len(expected) {
+		t.Fatalf("Expected %d groups, got %d: %+v", len(expected), len(cfg.Groups), cfg.Groups)
+	}
+	for _, group := range cfg.Groups {
+		patterns, exists := expected[group.Filename]
+		if !exists {
+			t.Errorf("Unexpected group for %s", group.Filename)
+			continue
+		}
+		if len(group.Patterns) != len(patterns) {
+			t.Errorf("Expected patterns %v for %s, got %v", patterns, group.Filename, group.Patterns)
+			continue
+		}
+		for i, pattern := range patterns {
+			if group.Patterns[i] != pattern {
+				t.Errorf("Expected patterns %v for %s, got %v", patterns, group.Filename, group.Patterns)
+				break
+			}
+		}
+	}
+
+	moves, err := scaffold.CountMoves(parsedFiles, cfg)
+	if err != nil {
+		t.Fatalf("CountMoves failed: %v", err)
+	}
+	if moves != 0 {
+		t.Errorf("Expected inferred config to reproduce the layout, got %d moves", moves)
+	}
+}
+
+func TestInferConfigDefaultLayout(t *testing.T) {
+	parsedFiles := &types.ParsedFiles{
+		Files: []*types.ParsedFile{
+			createTestFile("resource__aws_instance.tf", createTestBlock("resource", "aws_instance", "web")),
+			createTestFile("outputs.tf", createTestBlock("output", "id")),
+		},
+	}
+
+	cfg, err := scaffold.InferConfig(parsedFiles)
+	if err != nil {
+		t.Fatalf("InferConfig failed: %v", err)
+	}
+	if len(cfg.Groups) != 0 {
+		t.Errorf("Expected no groups for the default layout, got %+v", cfg.Groups)
+	}
+}
@@ -0,0 +1,108 @@
+package splitter
+
+import (
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/types"
+)
+
+// PatternCoverage counts how a single group pattern behaved against a codebase.
+type PatternCoverage struct {
+	Pattern string
+	Matches int // blocks with at least one candidate matching the pattern
+	Selects int // blocks whose placement was decided by the pattern
+}
+
+// GroupCoverage counts the blocks placed by a configured group.
+type GroupCoverage struct {
+	Name     string
+	Filename string
+	Blocks   int
+	Patterns []PatternCoverage
+}
+
+// FallThrough describes a block that no group placed.
+type FallThrough struct {
+	Address  string
+	FileName string
+	Excluded bool // a group matched but its filename is excluded
+}
+
+// CoverageReport summarizes how a configuration applies to a set of parsed files.
+type CoverageReport struct {
+	TotalBlocks int
+	Groups      []GroupCoverage
+	FallThrough []FallThrough
+}
+
+// Coverage reports, per group and per pattern, how many blocks matched and
+// which blocks fell through to default files. Fall-through blocks folded by
+// min_blocks are reported in the catch-all file, as a run would write them.
+func (s *Splitter) Coverage(parsedFiles *types.ParsedFiles) *CoverageReport {
+	report := &CoverageReport{}
+	index := make(map[string]int)
+	if s.config != nil {
+		for i, group := range s.config.Groups {
+			index[group.Name] = i
+			gc := GroupCoverage{Name: group.Name, Filename: group.Filename}
+			for _, pattern := range group.Patterns {
+				gc.Patterns = append(gc.Patterns, PatternCoverage{Pattern: pattern})
+			}
+			report.Groups = append(report.Groups, gc)
+		}
+	}
+
+	blocks := parsedFiles.AllBlocks()
+	folded := make(map[*types.Block]bool)
+	if catchAll := s.foldSmallGroups(s.groupByKey(blocks)); catchAll != nil {
+		for _, block := range catchAll.Blocks {
+			folded[block] = true
+		}
+	}
+
+	for _, block := range blocks {
+		report.TotalBlocks++
+
+		trace := &Explanation{Address: BlockAddress(block)}
+		_, filename := s.resolveGroup(block, trace)
+
+		for i := range report.Groups {
+			for j := range report.Groups[i].Patterns {
+				if trace.matched(report.Groups[i].Name, report.Groups[i].Patterns[j].Pattern) {
+					report.Groups[i].Patterns[j].Matches++
+				}
+			}
+		}
+
+		if folded[block] {
+			filename = s.config.CatchAllFilename()
+		}
+
+		if trace.MatchedGroup == "" || trace.Excluded {
+			report.FallThrough = append(report.FallThrough, FallThrough{
+				Address:  trace.Address,
+				FileName: filename,
+				Excluded: trace.Excluded,
+			})
+			continue
+		}
+
+		gc := &report.Groups[index[trace.MatchedGroup]]
+		gc.Blocks++
+		for j := range gc.Patterns {
+			if gc.Patterns[j].Pattern == trace.MatchedPattern {
+				gc.Patterns[j].Selects++
+			}
+		}
+	}
+
+	return report
+}
+
+// matched reports whether the pattern of the named group matched any candidate.
+func (e *Explanation) matched(group, pattern string) bool {
+	for _, check := range e.PatternChecks {
+		if check.Matched && check.Group == group && check.Pattern == pattern {
+			return true
+		}
+	}
+	return false
+}
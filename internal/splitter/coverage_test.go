@@ -0,0 +1,119 @@
+package splitter_test
+
+import (
+	"testing"
+
+	"github.com/tomoya-namekawa/tf-file-organize/internal/config"
+	"github.com/tomoya-namekawa/tf-file-organize/internal/splitter"
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/types"
+)
+
+func TestCoverage(t *testing.T) {
+	cfg := &config.Config{
+		Groups: []config.GroupConfig{
+			{
+				Name:     "network",
+				Filename: "network.tf",
+				Patterns: []string{"aws_vpc", "aws_subnet*", "aws_vcp"},
+			},
+			{
+				Name:     "special",
+				Filename: "special.tf",
+				Patterns: []string{"aws_instance"},
+			},
+		},
+		ExcludeFiles: []string{"special.tf"},
+	}
+
+	parsedFiles := &types.ParsedFiles{
+		Files: []*types.ParsedFile{{
+			Blocks: []*types.Block{
+				createTestBlock("resource", []string{"aws_vpc", "main"}),
+				createTestBlock("resource", []string{"aws_subnet", "a"}),
+				createTestBlock("resource", []string{"aws_subnet", "b"}),
+				createTestBlock("resource", []string{"aws_instance", "web"}),
+				createTestBlock("variable", []string{"region"}),
+			},
+		}},
+	}
+
+	report := splitter.NewWithConfig(cfg).Coverage(parsedFiles)
+
+	if report.TotalBlocks != 5 {
+		t.Errorf("Expected 5 blocks, got %d", report.TotalBlocks)
+	}
+
+	network := report.Groups[0]
+	if network.Blocks != 3 {
+		t.Errorf("Expected 3 blocks in network, got %d", network.Blocks)
+	}
+	expectedMatches := map[string]int{"aws_vpc": 1, "aws_subnet*": 2, "aws_vcp": 0}
+	for _, pattern := range network.Patterns {
+		if pattern.Matches != expectedMatches[pattern.Pattern] {
+			t.Errorf("Expected %d matches for %s, got %d", expectedMatches[pattern.Pattern], pattern.Pattern, pattern.Matches)
+		}
+	}
+
+	if report.Groups[1].Blocks != 0 {
+		t.Errorf("Expected excluded group to place no blocks, got %d", report.Groups[1].Blocks)
+	}
+
+	if len(report.FallThrough) != 2 {
+		t.Fatalf("Expected 2 fall-through blocks, got %d", len(report.FallThrough))
+	}
+	for _, block := range report.FallThrough {
+		switch block.Address {
+		case "resource.aws_instance.web":
+			if !block.Excluded || block.FileName != "resource__aws_instance.tf" {
+				t.Errorf("Unexpected fall-through for excluded block: %+v", block)
+			}
+		case "variable.region":
+			if block.Excluded || block.FileName != "variables.tf" {
+				t.Errorf("Unexpected fall-through for variable: %+v", block)
+			}
+		default:
+			t.Errorf("Unexpected fall-through block %s", block.Address)
+		}
+	}
+}
+
+func TestCoverageReportsFoldedBlocks(t *testing.T) {
+	cfg := &config.Config{
+		Groups: []config.GroupConfig{
+			{
+				Name:     "network",
+				Filename: "network.tf",
+				Patterns: []string{"aws_vpc"},
+			},
+		},
+		MinBlocks:    2,
+		CatchAllFile: "misc.tf",
+	}
+
+	parsedFiles := &types.ParsedFiles{
+		Files: []*types.ParsedFile{{
+			Blocks: []*types.Block{
+				createTestBlock("resource", []string{"aws_vpc", "main"}),
+				createTestBlock("resource", []string{"aws_instance", "web"}),
+				createTestBlock("resource", []string{"aws_s3_bucket", "logs"}),
+				createTestBlock("resource", []string{"aws_s3_bucket", "assets"}),
+			},
+		}},
+	}
+
+	report := splitter.NewWithConfig(cfg).Coverage(parsedFiles)
+
+	expected := map[string]string{
+		"resource.aws_instance.web":     "misc.tf",
+		"resource.aws_s3_bucket.logs":   "resource__aws_s3_bucket.tf",
+		"resource.aws_s3_bucket.assets": "resource__aws_s3_bucket.tf",
+	}
+	if len(report.FallThrough) != len(expected) {
+		t.Fatalf("Expected %d fall-through blocks, got %d", len(expected), len(report.FallThrough))
+	}
+	for _, block := range report.FallThrough {
+		if block.FileName != expected[block.Address] {
+			t.Errorf("Expected %s in %s, got %s", block.Address, expected[block.Address], block.FileName)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package splitter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tomoya-namekawa/tf-file-organize/internal/config"
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/types"
+)
+
+// PatternCheck records a single group pattern tested against a match candidate.
+type PatternCheck struct {
+	Candidate string
+	Group     string
+	Pattern   string
+	Matched   bool
+}
+
+// ExcludeCheck records a single exclude_files pattern tested against a group filename.
+type ExcludeCheck struct {
+	Filename string
+	Pattern  string
+	Matched  bool
+}
+
+// Explanation describes how the splitter decided where a block is written.
+type Explanation struct {
+	Address          string
+	SourceFile       string
+	Candidates       []string
+	PatternChecks    []PatternCheck
+	MatchedCandidate string
+	MatchedGroup     string
+	MatchedPattern   string
+	ExcludeChecks    []ExcludeCheck
+	Excluded         bool
+	GroupKey         string
+	GroupFileName    string
+	Folded           bool
+	FileName         string
+}
+
+// BlockAddress returns the dotted address of a block, e.g. "resource.aws_instance.web".
+func BlockAddress(block *types.Block) string {
+	return block.Address()
+}
+
+// FindBlocks returns every block whose address matches the given address.
+// Addresses without a known block type prefix are treated as resources,
+// so "aws_instance.web" is equivalent to "resource.aws_instance.web".
+func FindBlocks(parsedFiles *types.ParsedFiles, address string) []*types.Block {
+	normalized := address
+	switch strings.SplitN(address, ".", 2)[0] {
+	case blockTypeResource, blockTypeData, blockTypeModule, blockTypeProvider,
+		blockTypeVariable, blockTypeOutput, blockTypeLocals, blockTypeTerraform:
+	default:
+		normalized = blockTypeResource + "." + address
+	}
+
+	var blocks []*types.Block
+	for _, block := range parsedFiles.AllBlocks() {
+		if BlockAddress(block) == normalized {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+// Explain traces the grouping decision for block within the full set of parsed files.
+// The whole set is needed because folding by min_blocks depends on group sizes.
+func (s *Splitter) Explain(parsedFiles *types.ParsedFiles, block *types.Block) (*Explanation, error) {
+	groups, err := s.GroupBlocks(parsedFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	trace := &Explanation{
+		Address:    BlockAddress(block),
+		SourceFile: block.SourceFile,
+	}
+	trace.GroupKey, trace.GroupFileName = s.resolveGroup(block, trace)
+
+	for _, group := range groups {
+		for _, b := range group.Blocks {
+			if b == block {
+				trace.FileName = group.FileName
+			}
+		}
+	}
+	if trace.FileName == "" {
+		return nil, fmt.Errorf("block %s was not assigned to any group", trace.Address)
+	}
+	trace.Folded = trace.FileName != trace.GroupFileName
+
+	return trace, nil
+}
+
+func (s *Splitter) traceCandidates(candidates []string, trace *Explanation) {
+	for _, candidate := range candidates {
+		for _, group := range s.config.Groups {
+			for _, pattern := range group.Patterns {
+				trace.PatternChecks = append(trace.PatternChecks, PatternCheck{
+					Candidate: candidate,
+					Group:     group.Name,
+					Pattern:   pattern,
+					Matched:   s.config.MatchPattern(pattern, candidate),
+				})
+			}
+		}
+	}
+}
+
+func (e *Explanation) recordMatch(cfg *config.Config, group *config.GroupConfig, candidate, pattern string) {
+	e.MatchedCandidate = candidate
+	e.MatchedGroup = group.Name
+	e.MatchedPattern = pattern
+
+	for _, exclude := range cfg.ExcludeFiles {
+		matched := cfg.MatchPattern(exclude, group.Filename)
+		e.ExcludeChecks = append(e.ExcludeChecks, ExcludeCheck{
+			Filename: group.Filename,
+			Pattern:  exclude,
+			Matched:  matched,
+		})
+		if matched {
+			e.Excluded = true
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package splitter_test
+
+import (
+	"testing"
+
+	"github.com/tomoya-namekawa/tf-file-organize/internal/config"
+	"github.com/tomoya-namekawa/tf-file-organize/internal/splitter"
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/types"
+)
+
+func TestFindBlocks(t *testing.T) {
+	parsedFiles := &types.ParsedFiles{
+		Files: []*types.ParsedFile{{
+			Blocks: []*types.Block{
+				createTestBlock("resource", []string{"aws_instance", "web"}),
+				createTestBlock("data", []string{"aws_instance", "web"}),
+				createTestBlock("module", []string{"vpc"}),
+			},
+		}},
+	}
+
+	testCases := []struct {
+		address  string
+		expected int
+	}{
+		{"resource.aws_instance.web", 1},
+		{"aws_instance.web", 1},
+		{"data.aws_instance.web", 1},
+		{"module.vpc", 1},
+		{"resource.aws_instance.api", 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.address, func(t *testing.T) {
+			blocks := splitter.FindBlocks(parsedFiles, tc.address)
+			if len(blocks) != tc.expected {
+				t.Errorf("Expected %d blocks for %s, got %d", tc.expected, tc.address, len(blocks))
+			}
+		})
+	}
+}
+
+func TestExplain(t *testing.T) {
+	cfg := &config.Config{
+		Groups: []config.GroupConfig{
+			{
+				Name:     "network",
+				Filename: "network.tf",
+				Patterns: []string{"aws_vpc"},
+			},
+			{
+				Name:     "compute",
+				Filename: "compute-special.tf",
+				Patterns: []string{"resource.aws_instance.web*", "aws_instance"},
+			},
+		},
+		ExcludeFiles: []string{"*special*.tf"},
+		MinBlocks:    2,
+	}
+
+	web := createTestBlock("resource", []string{"aws_instance", "web"})
+	vpc := createTestBlock("resource", []string{"aws_vpc", "main"})
+	bucket := createTestBlock("resource", []string{"aws_s3_bucket", "logs"})
+	parsedFiles := &types.ParsedFiles{
+		Files: []*types.ParsedFile{{Blocks: []*types.Block{web, vpc, bucket}}},
+	}
+
+	s := splitter.NewWithConfig(cfg)
+
+	t.Run("excluded group falls back to per-type file", func(t *testing.T) {
+		e, err := s.Explain(parsedFiles, web)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(e.Candidates) != 4 {
+			t.Errorf("Expected 4 candidates, got %d", len(e.Candidates))
+		}
+		if e.MatchedGroup != "compute" || e.MatchedPattern != "resource.aws_instance.web*" {
+			t.Errorf("Expected match compute/resource.aws_instance.web*, got %s/%s", e.MatchedGroup, e.MatchedPattern)
+		}
+		if !e.Excluded {
+			t.Error("Expected group filename to be excluded")
+		}
+		if e.FileName != "main.tf" || !e.Folded {
+			t.Errorf("Expected folded into main.tf, got %s (folded=%v)", e.FileName, e.Folded)
+		}
+	})
+
+	t.Run("explicit group is kept", func(t *testing.T) {
+		e, err := s.Explain(parsedFiles, vpc)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if e.MatchedCandidate != "aws_vpc" {
+			t.Errorf("Expected candidate aws_vpc, got %s", e.MatchedCandidate)
+		}
+		if e.FileName != "network.tf" || e.Folded {
+			t.Errorf("Expected network.tf without folding, got %s (folded=%v)", e.FileName, e.Folded)
+		}
+	})
+}
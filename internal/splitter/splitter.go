@@ -50,9 +50,31 @@ func (s *Splitter) GroupBlocks(parsedFiles *types.ParsedFiles) ([]*types.BlockGr
 		return nil, err
 	}
 
+	groups := s.groupByKey(parsedFiles.AllBlocks())
+	catchAll := s.foldSmallGroups(groups)
+
+	result := make([]*types.BlockGroup, 0, len(groups)+1)
+	for _, group := range groups {
+		s.sortBlocksInGroup(group)
+		result = append(result, group)
+	}
+	if catchAll != nil {
+		s.sortBlocksInGroup(catchAll)
+		result = append(result, catchAll)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].FileName < result[j].FileName
+	})
+
+	return result, nil
+}
+
+// groupByKey collects blocks into groups keyed by their group key.
+func (s *Splitter) groupByKey(blocks []*types.Block) map[string]*types.BlockGroup {
 	groups := make(map[string]*types.BlockGroup)
 
-	for _, block := range parsedFiles.AllBlocks() {
+	for _, block := range blocks {
 		key, filename := s.getGroupKeyAndFilename(block)
 
 		if group, exists := groups[key]; exists {
@@ -67,34 +89,88 @@ func (s *Splitter) GroupBlocks(parsedFiles *types.ParsedFiles) ([]*types.BlockGr
 		}
 	}
 
-	result := make([]*types.BlockGroup, 0, len(groups))
-	for _, group := range groups {
-		s.sortBlocksInGroup(group)
-		result = append(result, group)
+	return groups
+}
+
+// foldSmallGroups merges per-type groups with fewer than MinBlocks blocks into
+// a catch-all group, which is removed from the map and returned (nil if nothing
+// was folded). Groups defined in the configuration are never folded.
+func (s *Splitter) foldSmallGroups(groups map[string]*types.BlockGroup) *types.BlockGroup {
+	if s.config == nil || s.config.MinBlocks <= 1 {
+		return nil
 	}
 
-	sort.Slice(result, func(i, j int) bool {
-		return result[i].FileName < result[j].FileName
-	})
+	configured := make(map[string]bool)
+	for _, group := range s.config.Groups {
+		configured[group.Name] = true
+	}
 
-	return result, nil
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var catchAll *types.BlockGroup
+	for _, key := range keys {
+		group := groups[key]
+		if configured[key] || !s.isFoldable(group) || len(group.Blocks) >= s.config.MinBlocks {
+			continue
+		}
+
+		if catchAll == nil {
+			catchAll = &types.BlockGroup{
+				BlockType: group.BlockType,
+				FileName:  s.config.CatchAllFilename(),
+			}
+		} else if catchAll.BlockType != group.BlockType {
+			catchAll.BlockType = "" // several block types
+		}
+		catchAll.Blocks = append(catchAll.Blocks, group.Blocks...)
+		delete(groups, key)
+	}
+
+	return catchAll
+}
+
+// isFoldable reports whether a group holds a single resource, data or module type
+func (s *Splitter) isFoldable(group *types.BlockGroup) bool {
+	switch group.BlockType {
+	case blockTypeResource, blockTypeData, blockTypeModule:
+		return group.SubType != ""
+	default:
+		return false
+	}
 }
 
 func (s *Splitter) getGroupKeyAndFilename(block *types.Block) (groupKey, filename string) {
+	return s.resolveGroup(block, nil)
+}
+
+// resolveGroup decides the group key and filename for a block. When trace is
+// non-nil every decision step is recorded in it.
+func (s *Splitter) resolveGroup(block *types.Block, trace *Explanation) (groupKey, filename string) {
 	resourceType := s.getSubType(block)
 
 	candidates := s.getMatchCandidates(block, resourceType)
+	if trace != nil {
+		trace.Candidates = candidates
+	}
 
 	if s.config != nil {
-		for _, candidate := range candidates {
-			if group := s.config.FindGroupForResource(candidate); group != nil {
-				if s.config.IsFileExcluded(group.Filename) {
-					key := s.getDefaultGroupKey(block)
-					fname := s.getExcludedFileName(block)
-					return key, fname
-				}
-				return group.Name, group.Filename
+		if trace != nil {
+			s.traceCandidates(candidates, trace)
+		}
+		if group, candidate, pattern := s.config.FindGroupForCandidates(candidates); group != nil {
+			if trace != nil {
+				trace.recordMatch(s.config, group, candidate, pattern)
+			}
+			if s.config.IsFileExcluded(group.Filename) {
+				key := s.getDefaultGroupKey(block)
+				fname := s.getExcludedFileName(block)
+				return key, fname
 			}
+			return group.Name, group.Filename
 		}
 	}
 
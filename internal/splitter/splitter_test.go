@@ -273,3 +273,71 @@ func TestCheckForDuplicateResources(t *testing.T) {
 		}
 	})
 }
+
+func TestGroupBlocksMinBlocks(t *testing.T) {
+	cfg := &config.Config{
+		Groups: []config.GroupConfig{
+			{
+				Name:     "compute",
+				Filename: "compute.tf",
+				Patterns: []string{"aws_instance"},
+			},
+		},
+		MinBlocks: 2,
+	}
+
+	s := splitter.NewWithConfig(cfg)
+
+	parsedFile := &types.ParsedFile{
+		Blocks: []*types.Block{
+			createTestBlock("variable", []string{"region"}),
+			createTestBlock("resource", []string{"aws_instance", "web"}),
+			createTestBlock("resource", []string{"aws_s3_bucket", "logs"}),
+			createTestBlock("resource", []string{"aws_iam_role", "app"}),
+			createTestBlock("resource", []string{"aws_iam_role", "ci"}),
+			createTestBlock("data", []string{"aws_ami", "ubuntu"}),
+			createTestBlock("module", []string{"vpc"}),
+		},
+	}
+
+	parsedFiles := &types.ParsedFiles{
+		Files: []*types.ParsedFile{parsedFile},
+	}
+	groups, err := s.GroupBlocks(parsedFiles)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	groupsByFileName := make(map[string]*types.BlockGroup)
+	for _, group := range groups {
+		groupsByFileName[group.FileName] = group
+	}
+
+	expected := map[string]int{
+		"compute.tf":                1, // explicit groups are never folded
+		"resource__aws_iam_role.tf": 2,
+		"variables.tf":              1,
+		"main.tf":                   3,
+	}
+	if len(groups) != len(expected) {
+		t.Errorf("Expected %d groups, got %d", len(expected), len(groups))
+	}
+	for fileName, count := range expected {
+		group, exists := groupsByFileName[fileName]
+		if !exists {
+			t.Errorf("%s group not found", fileName)
+			continue
+		}
+		if len(group.Blocks) != count {
+			t.Errorf("Expected %d blocks in %s, got %d", count, fileName, len(group.Blocks))
+		}
+	}
+
+	catchAll := groupsByFileName["main.tf"]
+	if catchAll != nil && catchAll.Blocks[0].Type != "data" {
+		t.Errorf("Expected catch-all blocks to be sorted, got first block type '%s'", catchAll.Blocks[0].Type)
+	}
+	if catchAll != nil && catchAll.BlockType != "" {
+		t.Errorf("Expected catch-all group of several block types to have no block type, got '%s'", catchAll.BlockType)
+	}
+}
@@ -1,6 +1,7 @@
 package usecase_test
 
 import (
+	"context"
 	"errors"
 	"testing"
 
@@ -157,7 +158,7 @@ func TestOrganizeFilesUsecase_ExecuteBusinessLogic(t *testing.T) {
 
 // testBusinessLogic tests business logic without file I/O
 func testBusinessLogic(_ *usecase.OrganizeFilesUsecase, blocks []*types.Block, configLoader *MockConfigLoader, splitter *MockSplitter, writer *MockWriter) (*usecase.OrganizeFilesResponse, error) {
-	_, err := configLoader.LoadConfig("")
+	_, err := configLoader.LoadConfig("", "")
 	if err != nil {
 		return nil, err
 	}
@@ -182,7 +183,7 @@ func testBusinessLogic(_ *usecase.OrganizeFilesUsecase, blocks []*types.Block, c
 		return nil, err
 	}
 
-	err = writer.WriteGroups(groups)
+	err = writer.WriteGroups(context.Background(), groups)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,31 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tomoya-namekawa/tf-file-organize/internal/config"
+	"github.com/tomoya-namekawa/tf-file-organize/internal/splitter"
+)
+
+type ConfigCoverageRequest struct {
+	InputPath string
+	Recursive bool
+	Config    *config.Config
+}
+
+// ConfigCoverage parses the Terraform files at the input path and reports how
+// the given configuration applies to them.
+func (uc *OrganizeFilesUsecase) ConfigCoverage(ctx context.Context, req *ConfigCoverageRequest) (*splitter.CoverageReport, error) {
+	stat, err := uc.fs.Stat(req.InputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access input path: %w", err)
+	}
+
+	parsedFiles, err := uc.parseInput(ctx, req.InputPath, stat, req.Recursive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse input: %w", err)
+	}
+
+	return splitter.NewWithConfig(req.Config).Coverage(parsedFiles), nil
+}
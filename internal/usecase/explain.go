@@ -0,0 +1,60 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/tomoya-namekawa/tf-file-organize/internal/splitter"
+)
+
+type ExplainBlockRequest struct {
+	InputPath  string
+	ConfigFile string
+	Recursive  bool
+	Address    string
+}
+
+type ExplainBlockResponse struct {
+	Explanations []*splitter.Explanation
+}
+
+// ExplainBlock traces how the blocks at the requested address are assigned to output files.
+func (uc *OrganizeFilesUsecase) ExplainBlock(ctx context.Context, req *ExplainBlockRequest) (*ExplainBlockResponse, error) {
+	stat, err := uc.fs.Stat(req.InputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access input path: %w", err)
+	}
+
+	searchDir := req.InputPath
+	if !stat.IsDir() {
+		searchDir = filepath.Dir(req.InputPath)
+	}
+
+	cfg, err := uc.configLoader.LoadConfig(req.ConfigFile, searchDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	parsedFiles, err := uc.parseInput(ctx, req.InputPath, stat, req.Recursive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse input: %w", err)
+	}
+
+	blocks := splitter.FindBlocks(parsedFiles, req.Address)
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("no block found with address '%s'", req.Address)
+	}
+
+	s := splitter.NewWithConfig(cfg)
+	resp := &ExplainBlockResponse{}
+	for _, block := range blocks {
+		explanation, err := s.Explain(parsedFiles, block)
+		if err != nil {
+			return nil, fmt.Errorf("failed to explain %s: %w", req.Address, err)
+		}
+		resp.Explanations = append(resp.Explanations, explanation)
+	}
+
+	return resp, nil
+}
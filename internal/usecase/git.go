@@ -0,0 +1,230 @@
+package usecase
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tomoya-namekawa/tf-file-organize/internal/gitutil"
+	"github.com/tomoya-namekawa/tf-file-organize/internal/manifest"
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/filesystem"
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/types"
+)
+
+// maxDirtyPaths limits the uncommitted paths listed in the dirty worktree error
+const maxDirtyPaths = 5
+
+// checkWorktree makes sure git mode can run on the input path: it must be in
+// a git worktree without uncommitted changes, unless they are allowed.
+func (uc *OrganizeFilesUsecase) checkWorktree(req *OrganizeFilesRequest, stat os.FileInfo) error {
+	repo, err := uc.openRepo(moduleDir(req.InputPath, stat))
+	if err != nil {
+		return err
+	}
+	if req.DryRun || req.AllowDirty {
+		return nil
+	}
+
+	dirty, err := repo.Dirty()
+	if err != nil {
+		return err
+	}
+	if len(dirty) > 0 {
+		listed := dirty
+		if len(listed) > maxDirtyPaths {
+			listed = append(listed[:maxDirtyPaths:maxDirtyPaths], "...")
+		}
+		return fmt.Errorf("worktree has uncommitted changes (%s); commit or stash them, or use --allow-dirty", strings.Join(listed, ", "))
+	}
+	return nil
+}
+
+// changedModules returns the absolute directories holding .tf files that
+// changed since req.Since, including deleted and untracked files.
+func (uc *OrganizeFilesUsecase) changedModules(req *OrganizeFilesRequest, stat os.FileInfo) (map[string]bool, error) {
+	repo, err := uc.openRepo(moduleDir(req.InputPath, stat))
+	if err != nil {
+		return nil, err
+	}
+	files, err := repo.ChangedFiles(req.Since)
+	if err != nil {
+		return nil, err
+	}
+
+	modules := make(map[string]bool)
+	for _, file := range files {
+		if strings.HasSuffix(file, ".tf") {
+			modules[filepath.Dir(file)] = true
+		}
+	}
+	uc.log.Debug("Found changed Terraform modules", "since", req.Since, "modules", len(modules))
+	return modules, nil
+}
+
+// inModules reports whether dir is one of modules, which hold absolute paths.
+func inModules(modules map[string]bool, dir string) bool {
+	abs, err := filepath.Abs(dir)
+	return err == nil && modules[abs]
+}
+
+// inputChanged reports whether the input of a run organizing a single module
+// holds changed modules: its directory, or in recursive mode any directory
+// under it.
+func (uc *OrganizeFilesUsecase) inputChanged(req *OrganizeFilesRequest, stat os.FileInfo, changed map[string]bool) bool {
+	dir := moduleDir(req.InputPath, stat)
+	if !req.Recursive || !stat.IsDir() {
+		return inModules(changed, dir)
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	for module := range changed {
+		if rel, err := filepath.Rel(abs, module); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// gitMoveSources moves each reorganized source file with git mv to the output
+// file receiving most of its blocks, before the output files are written, so
+// git follows the history of the file. Files are only moved to output files
+// that do not exist yet, one source file per output file, and only when they
+// are tracked. It returns the moves made, or planned in dry-run mode.
+func (uc *OrganizeFilesUsecase) gitMoveSources(req *OrganizeFilesRequest, stat os.FileInfo, outputDir string, groups []*types.BlockGroup, filesToRemove []string) ([]types.FileOperation, error) {
+	if !req.Git || len(filesToRemove) == 0 || outputDir != moduleDir(req.InputPath, stat) {
+		return nil, nil
+	}
+	repo, err := gitutil.Open(outputDir)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := mainOutputFiles(groups)
+	claimed := make(map[string]bool)
+	var moves []types.FileOperation
+	for _, source := range filesToRemove {
+		fileName, ok := targets[source]
+		if !ok || claimed[fileName] {
+			continue
+		}
+		target := filepath.Join(outputDir, fileName)
+		if _, err := uc.fs.Stat(target); err == nil {
+			continue // merged into the existing file instead
+		}
+		tracked, err := repo.Tracked(source)
+		if err != nil {
+			return nil, err
+		}
+		if !tracked {
+			continue
+		}
+
+		claimed[fileName] = true
+		if req.DryRun {
+			uc.log.Info("Would move file", "path", source, "target", target)
+		} else {
+			if err := repo.Move(source, target); err != nil {
+				return nil, fmt.Errorf("failed to move %s: %w", source, err)
+			}
+			uc.log.Info("Moved file", "path", source, "target", target)
+		}
+		moves = append(moves, types.FileOperation{Action: types.ActionMove, Path: source, Target: target})
+	}
+	return moves, nil
+}
+
+// mainOutputFiles maps each source file to the output file receiving most of
+// its blocks. Ties go to the output file of the earlier group.
+func mainOutputFiles(groups []*types.BlockGroup) map[string]string {
+	counts := make(map[string]map[string]int)
+	for _, group := range groups {
+		for _, block := range group.Blocks {
+			if counts[block.SourceFile] == nil {
+				counts[block.SourceFile] = make(map[string]int)
+			}
+			counts[block.SourceFile][group.FileName]++
+		}
+	}
+
+	targets := make(map[string]string)
+	for _, group := range groups {
+		for source, perFile := range counts {
+			if best, ok := targets[source]; !ok || perFile[group.FileName] > perFile[best] {
+				targets[source] = group.FileName
+			}
+		}
+	}
+	return targets
+}
+
+// unmovedFiles returns the files that were not moved by git.
+func unmovedFiles(files []string, operations []types.FileOperation) []string {
+	moved := make(map[string]bool)
+	for _, op := range operations {
+		if op.Action == types.ActionMove {
+			moved[op.Path] = true
+		}
+	}
+	var remaining []string
+	for _, file := range files {
+		if !moved[file] {
+			remaining = append(remaining, file)
+		}
+	}
+	return remaining
+}
+
+// gitStage stages every file created, updated, moved or removed by the run,
+// and the manifest of the output directory.
+func (uc *OrganizeFilesUsecase) gitStage(req *OrganizeFilesRequest, stat os.FileInfo, outputDir string, operations []types.FileOperation) error {
+	if !req.Git || req.DryRun {
+		return nil
+	}
+	repo, err := gitutil.Open(moduleDir(req.InputPath, stat))
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool)
+	var paths []string
+	add := func(path string) {
+		if path != "" && !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	for _, op := range operations {
+		add(op.Path)
+		add(op.Target)
+	}
+	add(filepath.Join(outputDir, manifest.FileName))
+
+	exists := func(path string) bool {
+		_, err := uc.fs.Stat(path)
+		return err == nil
+	}
+	if err := repo.Stage(paths, exists); err != nil {
+		return fmt.Errorf("failed to stage changes: %w", err)
+	}
+	uc.log.Info("Staged changes", "files", len(paths))
+	return nil
+}
+
+// openRepo opens the git repository containing dir.
+func (uc *OrganizeFilesUsecase) openRepo(dir string) (*gitutil.Repo, error) {
+	if uc.fs != filesystem.OS() {
+		return nil, fmt.Errorf("git integration requires the operating system file system")
+	}
+	return gitutil.Open(dir)
+}
+
+// moduleDir returns the directory of the input path.
+func moduleDir(inputPath string, stat os.FileInfo) string {
+	if stat.IsDir() {
+		return inputPath
+	}
+	return filepath.Dir(inputPath)
+}
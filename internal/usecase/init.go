@@ -0,0 +1,90 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/tomoya-namekawa/tf-file-organize/internal/config"
+	"github.com/tomoya-namekawa/tf-file-organize/internal/scaffold"
+)
+
+const defaultConfigFileName = "tf-file-organize.yaml"
+
+const initConfigHeader = `# Generated by tf-file-organize init from the existing file layout.
+# Review the groups below, then run 'tf-file-organize plan' to preview.
+`
+
+type InitConfigRequest struct {
+	InputPath  string
+	ConfigFile string // default: tf-file-organize.yaml in InputPath
+	Force      bool
+	DryRun     bool
+}
+
+type InitConfigResponse struct {
+	Config      *config.Config
+	ConfigFile  string
+	Content     []byte
+	TotalBlocks int
+	Moves       int // blocks that would still change file with the inferred config
+}
+
+// InitConfig infers a configuration that reproduces the current layout of the
+// Terraform files in a directory and writes it as a config file.
+func (uc *OrganizeFilesUsecase) InitConfig(ctx context.Context, req *InitConfigRequest) (*InitConfigResponse, error) {
+	stat, err := uc.fs.Stat(req.InputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to access input path: %w", err)
+	}
+	if !stat.IsDir() {
+		return nil, fmt.Errorf("input path must be a directory: %s", req.InputPath)
+	}
+
+	configFile := req.ConfigFile
+	if configFile == "" {
+		configFile = filepath.Join(req.InputPath, defaultConfigFileName)
+	}
+	if _, statErr := uc.fs.Stat(configFile); statErr == nil && !req.Force && !req.DryRun {
+		return nil, fmt.Errorf("config file already exists: %s (use --force to overwrite)", configFile)
+	}
+
+	parsedFiles, err := uc.parseInput(ctx, req.InputPath, stat, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse input: %w", err)
+	}
+
+	cfg, err := scaffold.InferConfig(parsedFiles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to infer config: %w", err)
+	}
+	if err := config.ValidateConfig(cfg); err != nil {
+		return nil, fmt.Errorf("inferred config is invalid: %w", err)
+	}
+	cfg.Version = config.CurrentVersion
+
+	moves, err := scaffold.CountMoves(parsedFiles, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check inferred config: %w", err)
+	}
+
+	data, err := config.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	content := append([]byte(initConfigHeader), data...)
+
+	if !req.DryRun {
+		if err := uc.fs.WriteFile(configFile, content, 0600); err != nil {
+			return nil, fmt.Errorf("failed to write config file %s: %w", configFile, err)
+		}
+	}
+
+	return &InitConfigResponse{
+		Config:      cfg,
+		ConfigFile:  configFile,
+		Content:     content,
+		TotalBlocks: parsedFiles.TotalBlocks(),
+		Moves:       moves,
+	}, nil
+}
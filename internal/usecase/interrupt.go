@@ -0,0 +1,95 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// InterruptedError reports a run stopped because its context was canceled,
+// and the state it left the modules in. Every module is either organized or
+// unchanged, unless rolling back the module being written failed.
+type InterruptedError struct {
+	Organized   []string // Modules organized completely
+	Unchanged   []string // Modules left as they were, including a rolled back one
+	RolledBack  string   // Module whose partial changes were undone, if any
+	RollbackErr error    // Why undoing the changes to RolledBack failed, if it did
+	DryRun      bool     // The run was not going to change any file
+	Err         error    // Error the run stopped with, wrapping the context error
+}
+
+func (e *InterruptedError) Error() string {
+	switch {
+	case e.RollbackErr != nil:
+		return fmt.Sprintf("interrupted while writing %s, and rolling it back failed: %v; it may hold duplicate blocks", e.RolledBack, e.RollbackErr)
+	case e.DryRun || len(e.Organized) == 0:
+		return "interrupted: no files were changed"
+	default:
+		return fmt.Sprintf("interrupted: %d of %d modules organized, the others are unchanged",
+			len(e.Organized), len(e.Organized)+len(e.Unchanged))
+	}
+}
+
+func (e *InterruptedError) Unwrap() error {
+	return e.Err
+}
+
+// interrupted builds the error of a run canceled before the modules in
+// pending were organized and logs the state of every module. err is the
+// error the run stopped with, which tells whether the first pending module
+// was rolled back.
+func (uc *OrganizeFilesUsecase) interrupted(req *OrganizeFilesRequest, organized, pending []string, err error) error {
+	ie := &InterruptedError{
+		Organized: slices.Clone(organized),
+		DryRun:    req.DryRun,
+		Err:       err,
+	}
+	var rollback *rollbackError
+	if errors.As(err, &rollback) && len(pending) > 0 {
+		ie.RolledBack = pending[0]
+		if rollback.rollbackErr != nil {
+			ie.RollbackErr = rollback.rollbackErr
+			pending = pending[1:]
+		}
+	}
+	ie.Unchanged = slices.Clone(pending)
+	if req.DryRun {
+		ie.Unchanged = append(ie.Organized, ie.Unchanged...)
+		ie.Organized = nil
+		return ie
+	}
+
+	for _, dir := range ie.Organized {
+		uc.log.Warn("Organized before the interruption", "path", dir)
+	}
+	if ie.RollbackErr != nil {
+		uc.log.Error("Failed to roll back", "path", ie.RolledBack, "error", ie.RollbackErr)
+	}
+	for _, dir := range ie.Unchanged {
+		uc.log.Warn("Left unchanged", "path", dir)
+	}
+	return ie
+}
+
+// isCanceled reports whether err was caused by the cancellation of ctx.
+func isCanceled(ctx context.Context, err error) bool {
+	return err != nil && ctx.Err() != nil && errors.Is(err, ctx.Err())
+}
+
+// rollbackError reports a module whose changes were undone after err.
+type rollbackError struct {
+	err         error
+	rollbackErr error // why undoing the changes failed, if it did
+}
+
+func (e *rollbackError) Error() string {
+	if e.rollbackErr != nil {
+		return fmt.Sprintf("%v (rolling back the changes failed: %v)", e.err, e.rollbackErr)
+	}
+	return fmt.Sprintf("%v (changes rolled back)", e.err)
+}
+
+func (e *rollbackError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,146 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/filesystem"
+)
+
+// journal wraps a file system and records the original state of every path
+// before it is first changed, so that the changes can be rolled back.
+type journal struct {
+	filesystem.FileSystem
+
+	mu      sync.Mutex
+	entries []journalEntry
+	saved   map[string]bool
+}
+
+// journalEntry is the state of a path before it was changed.
+type journalEntry struct {
+	path    string
+	dir     bool // a directory created through the journal
+	existed bool
+	content []byte
+	mode    fs.FileMode
+}
+
+func newJournal(fsys filesystem.FileSystem) *journal {
+	return &journal{FileSystem: fsys, saved: make(map[string]bool)}
+}
+
+func (j *journal) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	if err := j.save(name); err != nil {
+		return err
+	}
+	return j.FileSystem.WriteFile(name, data, perm)
+}
+
+func (j *journal) Chmod(name string, mode fs.FileMode) error {
+	if err := j.save(name); err != nil {
+		return err
+	}
+	return j.FileSystem.Chmod(name, mode)
+}
+
+func (j *journal) Rename(oldpath, newpath string) error {
+	if err := j.save(oldpath); err != nil {
+		return err
+	}
+	if err := j.save(newpath); err != nil {
+		return err
+	}
+	return j.FileSystem.Rename(oldpath, newpath)
+}
+
+func (j *journal) Remove(name string) error {
+	if err := j.save(name); err != nil {
+		return err
+	}
+	return j.FileSystem.Remove(name)
+}
+
+func (j *journal) MkdirAll(path string, perm fs.FileMode) error {
+	// Record the missing directories, outermost first
+	var missing []string
+	for dir := filepath.Clean(path); ; dir = filepath.Dir(dir) {
+		if _, err := j.FileSystem.Stat(dir); err == nil || !errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		missing = append([]string{dir}, missing...)
+		if parent := filepath.Dir(dir); parent == dir {
+			break
+		}
+	}
+	j.mu.Lock()
+	for _, dir := range missing {
+		j.entries = append(j.entries, journalEntry{path: dir, dir: true})
+	}
+	j.mu.Unlock()
+	return j.FileSystem.MkdirAll(path, perm)
+}
+
+// save records the state of the file at name unless it was already recorded.
+func (j *journal) save(name string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.saved[name] {
+		return nil
+	}
+
+	entry := journalEntry{path: name}
+	info, err := j.FileSystem.Stat(name)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return fmt.Errorf("failed to record the state of %s: %w", name, err)
+	case info.IsDir():
+		return fmt.Errorf("cannot record the state of directory %s", name)
+	default:
+		content, err := j.FileSystem.ReadFile(name)
+		if err != nil {
+			return fmt.Errorf("failed to record the state of %s: %w", name, err)
+		}
+		entry.existed, entry.content, entry.mode = true, content, info.Mode().Perm()
+	}
+	j.saved[name] = true
+	j.entries = append(j.entries, entry)
+	return nil
+}
+
+// rollback restores every recorded path to its original state, latest change
+// first, and returns the errors it could not recover from.
+func (j *journal) rollback() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var errs []error
+	for i := len(j.entries) - 1; i >= 0; i-- {
+		entry := j.entries[i]
+		switch {
+		case entry.dir:
+			// A directory that is not empty holds files kept by someone else
+			_ = j.FileSystem.Remove(entry.path)
+		case entry.existed:
+			if err := j.FileSystem.WriteFile(entry.path, entry.content, entry.mode); err != nil {
+				errs = append(errs, fmt.Errorf("failed to restore %s: %w", entry.path, err))
+				continue
+			}
+			// WriteFile keeps the mode of an existing file
+			if err := j.FileSystem.Chmod(entry.path, entry.mode); err != nil {
+				errs = append(errs, fmt.Errorf("failed to restore the mode of %s: %w", entry.path, err))
+			}
+		default:
+			if err := j.FileSystem.Remove(entry.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, fmt.Errorf("failed to remove %s: %w", entry.path, err))
+			}
+		}
+	}
+	j.entries, j.saved = nil, make(map[string]bool)
+	return errors.Join(errs...)
+}
@@ -0,0 +1,68 @@
+package usecase
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/tomoya-namekawa/tf-file-organize/internal/dirlock"
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/filesystem"
+)
+
+// lockOutputDir takes the advisory lock of outputDir, so another run cannot
+// organize it at the same time, and returns the function releasing it. Only
+// the OS file system is locked: other file systems are private to this run.
+func (uc *OrganizeFilesUsecase) lockOutputDir(outputDir string) (unlock func(), err error) {
+	if uc.fs != filesystem.OS() {
+		return func() {}, nil
+	}
+	lock, stale, err := dirlock.Acquire(outputDir)
+	if err != nil {
+		return nil, err
+	}
+	if stale != nil {
+		uc.log.Warn("Replaced stale lock", "path", outputDir, "pid", stale.PID, "host", stale.Host, "started", stale.Started.Format(time.RFC3339))
+	}
+	return func() {
+		if err := lock.Release(); err != nil {
+			uc.log.Warn("Failed to release lock", "path", outputDir, "error", err)
+		}
+	}, nil
+}
+
+// sourceDigest returns a hash of the Terraform files the module at
+// req.InputPath is organized from. A module planned before its output
+// directory was locked is planned again if the digest changed meanwhile, so a
+// concurrent run's changes are never overwritten with a stale plan. It is
+// empty for runs that take no lock.
+func (uc *OrganizeFilesUsecase) sourceDigest(req *OrganizeFilesRequest, stat os.FileInfo) (string, error) {
+	if req.DryRun || uc.fs != filesystem.OS() {
+		return "", nil
+	}
+	paths := []string{req.InputPath}
+	if stat.IsDir() {
+		var err error
+		paths, _, err = uc.listDirectory(req.InputPath, req.Recursive)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	h := sha256.New()
+	for _, path := range paths {
+		content, err := uc.fs.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue // removed since listed; the digest still differs
+		}
+		if err != nil {
+			return "", err
+		}
+		_, _ = fmt.Fprintf(h, "%s\x00%d\x00", path, len(content))
+		_, _ = h.Write(content)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
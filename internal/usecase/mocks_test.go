@@ -1,6 +1,8 @@
 package usecase_test
 
 import (
+	"context"
+
 	"github.com/tomoya-namekawa/tf-file-organize/internal/config"
 	"github.com/tomoya-namekawa/tf-file-organize/pkg/types"
 )
@@ -10,7 +12,7 @@ type MockParser struct {
 	parseFileFunc func(filename string) (*types.ParsedFile, error)
 }
 
-func (m *MockParser) ParseFile(filename string) (*types.ParsedFile, error) {
+func (m *MockParser) ParseFile(_ context.Context, filename string) (*types.ParsedFile, error) {
 	if m.parseFileFunc != nil {
 		return m.parseFileFunc(filename)
 	}
@@ -50,7 +52,7 @@ type MockWriter struct {
 	writeGroupsFunc func(groups []*types.BlockGroup) error
 }
 
-func (m *MockWriter) WriteGroups(groups []*types.BlockGroup) error {
+func (m *MockWriter) WriteGroups(_ context.Context, groups []*types.BlockGroup) error {
 	if m.writeGroupsFunc != nil {
 		return m.writeGroupsFunc(groups)
 	}
@@ -63,7 +65,7 @@ type MockConfigLoader struct {
 	loadConfigFunc func(configPath string) (*config.Config, error)
 }
 
-func (m *MockConfigLoader) LoadConfig(configPath string) (*config.Config, error) {
+func (m *MockConfigLoader) LoadConfig(configPath, _ string) (*config.Config, error) {
 	if m.loadConfigFunc != nil {
 		return m.loadConfigFunc(configPath)
 	}
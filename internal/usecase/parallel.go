@@ -0,0 +1,134 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/tomoya-namekawa/tf-file-organize/internal/logging"
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/types"
+)
+
+// SetJobs sets how many files are parsed, and how many modules are planned
+// ahead, at the same time. Zero or less means the number of CPUs. Results and
+// events are reported in the same order whatever the number of jobs.
+func (uc *OrganizeFilesUsecase) SetJobs(jobs int) {
+	if jobs <= 0 {
+		jobs = runtime.GOMAXPROCS(0)
+	}
+	uc.jobs = jobs
+	uc.parseSlots = make(chan struct{}, jobs)
+}
+
+// parseFiles parses paths concurrently, with at most one file per job being
+// parsed across all modules, and returns the files in the order of paths.
+// Files that fail to parse are skipped with a warning. It fails once ctx is
+// canceled.
+func (uc *OrganizeFilesUsecase) parseFiles(ctx context.Context, paths []string) (*types.ParsedFiles, error) {
+	files := make([]*types.ParsedFile, len(paths))
+	errs := make([]error, len(paths))
+
+	var wg sync.WaitGroup
+	for i, path := range paths {
+		select {
+		case uc.parseSlots <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return nil, ctx.Err()
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-uc.parseSlots }()
+			files[i], errs[i] = uc.parser.ParseFile(ctx, path)
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	parsedFiles := &types.ParsedFiles{
+		Files: make([]*types.ParsedFile, 0, len(paths)),
+	}
+	for i, path := range paths {
+		if errs[i] != nil {
+			uc.log.Warn("failed to parse file", "path", path, "error", errs[i])
+			continue // Continue with warning only for file errors
+		}
+		uc.log.Debug("Parsed file", "path", path, "blocks", len(files[i].Blocks))
+		parsedFiles.Files = append(parsedFiles.Files, files[i])
+	}
+	return parsedFiles, nil
+}
+
+// plannedModule is a module of a recursive run whose read-only steps ran
+// ahead of its turn to be written.
+type plannedModule struct {
+	req   *OrganizeFilesRequest
+	plan  *modulePlan
+	err   error
+	flush func() // reports the events logged while planning
+}
+
+// planModules plans the modules in dirs concurrently and returns the channel
+// each module's plan is delivered on, in the order of dirs. A module holds a
+// job from the start of its planning until release is called once it has been
+// applied, so at most uc.jobs plans are kept in memory. Planning stops
+// starting new modules when stop is closed or ctx is canceled.
+func (uc *OrganizeFilesUsecase) planModules(ctx context.Context, req *OrganizeFilesRequest, dirs []string, stop <-chan struct{}) (plans []chan plannedModule, release func()) {
+	slots := make(chan struct{}, uc.jobs)
+	plans = make([]chan plannedModule, len(dirs))
+	for i := range plans {
+		plans[i] = make(chan plannedModule, 1)
+	}
+
+	go func() {
+		for i, dir := range dirs {
+			select {
+			case slots <- struct{}{}:
+			case <-stop:
+				return
+			case <-ctx.Done():
+				return
+			}
+			go func() { plans[i] <- uc.planModuleIn(ctx, req, dir) }()
+		}
+	}()
+	return plans, func() { <-slots }
+}
+
+// planModuleIn plans the module in dir, holding back the events it logs so
+// modules planned concurrently report in order.
+func (uc *OrganizeFilesUsecase) planModuleIn(ctx context.Context, req *OrganizeFilesRequest, dir string) plannedModule {
+	mc, flush := uc.withBufferedLog()
+	mc.log.Info("Organizing module", "path", dir)
+	moduleReq := *req
+	moduleReq.InputPath = dir
+	moduleReq.Recursive = false
+
+	stat, err := mc.fs.Stat(dir)
+	if err != nil {
+		return plannedModule{err: fmt.Errorf("failed to access directory %s: %w", dir, err), flush: flush}
+	}
+	plan, err := mc.planModule(ctx, &moduleReq, stat)
+	if err != nil {
+		err = fmt.Errorf("module %s: %w", dir, err)
+	}
+	return plannedModule{req: &moduleReq, plan: plan, err: err, flush: flush}
+}
+
+// withBufferedLog returns a copy of uc whose events, including those of the
+// default config loader, are held until flush is called.
+func (uc *OrganizeFilesUsecase) withBufferedLog() (mc *OrganizeFilesUsecase, flush func()) {
+	log, flush := logging.Buffer(uc.log)
+	clone := *uc
+	clone.log = log
+	if loader, ok := uc.configLoader.(*DefaultConfigLoader); ok {
+		buffered := *loader
+		buffered.Logger = log
+		clone.configLoader = &buffered
+	}
+	return &clone, flush
+}
@@ -1,15 +1,24 @@
 package usecase
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/tomoya-namekawa/tf-file-organize/internal/config"
+	"github.com/tomoya-namekawa/tf-file-organize/internal/fileformat"
+	"github.com/tomoya-namekawa/tf-file-organize/internal/logging"
+	"github.com/tomoya-namekawa/tf-file-organize/internal/manifest"
 	"github.com/tomoya-namekawa/tf-file-organize/internal/parser"
 	"github.com/tomoya-namekawa/tf-file-organize/internal/splitter"
 	"github.com/tomoya-namekawa/tf-file-organize/internal/writer"
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/filesystem"
 	"github.com/tomoya-namekawa/tf-file-organize/pkg/types"
 )
 
@@ -19,10 +28,12 @@ const (
 	providersFile = "providers.tf"
 	terraformFile = "terraform.tf"
 	variablesFile = "variables.tf"
+
+	backupDirName = "backup"
 )
 
 type ParserInterface interface {
-	ParseFile(filename string) (*types.ParsedFile, error)
+	ParseFile(ctx context.Context, filename string) (*types.ParsedFile, error)
 }
 
 type SplitterInterface interface {
@@ -30,20 +41,26 @@ type SplitterInterface interface {
 }
 
 type WriterInterface interface {
-	WriteGroups(groups []*types.BlockGroup) error
+	WriteGroups(ctx context.Context, groups []*types.BlockGroup) error
 }
 
 type ConfigLoaderInterface interface {
-	LoadConfig(configPath string) (*config.Config, error)
+	LoadConfig(configPath, searchDir string) (*config.Config, error)
 }
 
 type OrganizeFilesRequest struct {
-	InputPath  string
-	OutputDir  string
-	ConfigFile string
-	DryRun     bool
-	Recursive  bool
-	Backup     bool
+	InputPath       string
+	OutputDir       string
+	ConfigFile      string
+	DryRun          bool
+	Recursive       bool
+	PerDirectory    bool // with Recursive, organize each directory in place instead of combining them
+	Backup          bool
+	Force           bool   // overwrite existing target files whose content cannot be merged
+	NormalizeBlocks bool   // reorder attributes inside blocks into canonical order
+	Git             bool   // git mv source files to their main output file and stage the changes
+	AllowDirty      bool   // allow git mode on a worktree with uncommitted changes
+	Since           string // only organize modules with .tf files changed since this git ref
 }
 
 type OrganizeFilesResponse struct {
@@ -52,6 +69,9 @@ type OrganizeFilesResponse struct {
 	FileGroups     int
 	OutputDir      string
 	WasDryRun      bool
+	StaleFiles     []string              // previously generated files no longer produced
+	Groups         []*types.BlockGroup   // blocks grouped by output file
+	Operations     []types.FileOperation // files written, removed or backed up (planned in dry-run mode)
 }
 
 type OrganizeFilesUsecase struct {
@@ -59,81 +79,347 @@ type OrganizeFilesUsecase struct {
 	splitter     SplitterInterface
 	writer       WriterInterface
 	configLoader ConfigLoaderInterface
+	log          *slog.Logger
+	fs           filesystem.FileSystem
+	jobs         int           // files parsed and modules planned at the same time
+	parseSlots   chan struct{} // one slot per file being parsed, shared by all modules
 }
 
 func NewOrganizeFilesUsecase() *OrganizeFilesUsecase {
-	return &OrganizeFilesUsecase{
+	uc := &OrganizeFilesUsecase{
 		parser:       parser.New(),
 		splitter:     nil, // Initialized with configuration in Execute
 		writer:       nil, // Initialized in Execute
 		configLoader: &DefaultConfigLoader{},
+		log:          slog.Default(),
+		fs:           filesystem.OS(),
 	}
+	uc.SetJobs(0)
+	return uc
 }
 
 func NewOrganizeFilesUsecaseWithDeps(p ParserInterface, s SplitterInterface, w WriterInterface, c ConfigLoaderInterface) *OrganizeFilesUsecase {
-	return &OrganizeFilesUsecase{
+	uc := &OrganizeFilesUsecase{
 		parser:       p,
 		splitter:     s,
 		writer:       w,
 		configLoader: c,
+		log:          slog.Default(),
+		fs:           filesystem.OS(),
+	}
+	uc.SetJobs(0)
+	return uc
+}
+
+// SetLogger sends progress and warning events, including those of the
+// default config loader and writer, to logger. Nil discards them.
+func (uc *OrganizeFilesUsecase) SetLogger(logger *slog.Logger) {
+	uc.log = logging.OrDiscard(logger)
+	if loader, ok := uc.configLoader.(*DefaultConfigLoader); ok {
+		loader.Logger = uc.log
+	}
+}
+
+// SetFileSystem makes the usecase read and write Terraform files through fsys,
+// including with the default parser and writer. Configuration files are still
+// read from the operating system, and only when given explicitly: discovery is
+// disabled for file systems other than the OS.
+func (uc *OrganizeFilesUsecase) SetFileSystem(fsys filesystem.FileSystem) {
+	uc.fs = fsys
+	if _, ok := uc.parser.(*parser.Parser); ok {
+		uc.parser = parser.NewWithFS(fsys)
+	}
+	if loader, ok := uc.configLoader.(*DefaultConfigLoader); ok {
+		loader.SkipDiscovery = fsys != filesystem.OS()
 	}
 }
 
-type DefaultConfigLoader struct{}
+type DefaultConfigLoader struct {
+	// Logger receives progress and warning events. Nil means slog.Default().
+	Logger *slog.Logger
+	// SkipDiscovery disables searching for configuration files when no
+	// configuration path is given.
+	SkipDiscovery bool
+}
+
+// LoadConfig loads configPath if given. Otherwise configuration files are
+// discovered by walking up from searchDir to the repository root, falling back
+// to the current working directory.
+func (d *DefaultConfigLoader) LoadConfig(configPath, searchDir string) (*config.Config, error) {
+	cfg, err := d.loadConfig(configPath, searchDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, note := range cfg.Migrations {
+		d.logger().Warn("configuration uses an older format; run 'tf-file-organize migrate-config' to update the file", "change", note)
+	}
+	return cfg, nil
+}
 
-func (d *DefaultConfigLoader) LoadConfig(configPath string) (*config.Config, error) {
+func (d *DefaultConfigLoader) loadConfig(configPath, searchDir string) (*config.Config, error) {
 	if configPath != "" {
-		fmt.Printf("Loading configuration from: %s\n", configPath)
+		d.logger().Info("Loading configuration", "file", configPath)
 		return config.LoadConfig(configPath)
 	}
-
-	defaultConfigs := []string{
-		"tf-file-organize.yaml",
-		"tf-file-organize.yml",
-		".tf-file-organize.yaml",
-		".tf-file-organize.yml",
+	if d.SkipDiscovery {
+		return &config.Config{}, nil
 	}
 
-	for _, defaultConfig := range defaultConfigs {
-		if _, err := os.Stat(defaultConfig); err == nil {
-			fmt.Printf("Loading configuration from: %s\n", defaultConfig)
-			return config.LoadConfig(defaultConfig)
+	if searchDir != "" {
+		cfg, files, err := config.LoadDiscovered(searchDir)
+		if err != nil {
+			return nil, err
+		}
+		if len(files) > 0 {
+			for i := len(files) - 1; i >= 0; i-- {
+				d.logger().Info("Loading configuration", "file", files[i])
+			}
+			return cfg, nil
 		}
 	}
 
+	if defaultConfig := config.FindInDir("."); defaultConfig != "" {
+		d.logger().Info("Loading configuration", "file", defaultConfig)
+		return config.LoadConfig(defaultConfig)
+	}
+
 	return &config.Config{}, nil
 }
 
+func (d *DefaultConfigLoader) logger() *slog.Logger {
+	if d.Logger == nil {
+		return slog.Default()
+	}
+	return d.Logger
+}
+
 // Execute performs the main business logic for organizing Terraform files.
-func (uc *OrganizeFilesUsecase) Execute(req *OrganizeFilesRequest) (*OrganizeFilesResponse, error) {
-	// 1. Prepare: input validation and config loading
-	stat, err := os.Stat(req.InputPath)
+// In recursive mode the .tf files of every subdirectory are combined into the
+// output directory. With PerDirectory, every directory containing .tf files
+// is instead organized in place as its own module, with the configuration
+// discovered for that directory.
+//
+// Each module is changed atomically. When ctx is canceled, the module being
+// written is rolled back, or finished if its files are all written, and an
+// *InterruptedError reports which modules were organized.
+func (uc *OrganizeFilesUsecase) Execute(ctx context.Context, req *OrganizeFilesRequest) (*OrganizeFilesResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	stat, err := uc.fs.Stat(req.InputPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to access input path: %w", err)
 	}
 
+	if req.Git {
+		if err := uc.checkWorktree(req, stat); err != nil {
+			return nil, err
+		}
+	}
+
+	var changed map[string]bool
+	if req.Since != "" {
+		if changed, err = uc.changedModules(req, stat); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Recursive && req.PerDirectory && stat.IsDir() {
+		return uc.executeRecursive(ctx, req, changed)
+	}
+	if changed != nil && !uc.inputChanged(req, stat, changed) {
+		uc.log.Info("No Terraform files changed", "since", req.Since, "path", req.InputPath)
+		outputDir := req.OutputDir
+		if outputDir == "" {
+			outputDir = moduleDir(req.InputPath, stat)
+		}
+		return &OrganizeFilesResponse{OutputDir: outputDir, WasDryRun: req.DryRun}, nil
+	}
+	resp, err := uc.executeModule(ctx, req, stat)
+	if isCanceled(ctx, err) {
+		return nil, uc.interrupted(req, nil, []string{req.InputPath}, err)
+	}
+	return resp, err
+}
+
+// executeRecursive organizes every module under the input path. When changed
+// is set, only the modules it holds are organized.
+func (uc *OrganizeFilesUsecase) executeRecursive(ctx context.Context, req *OrganizeFilesRequest, changed map[string]bool) (*OrganizeFilesResponse, error) {
+	uc.log.Info("Scanning directory recursively for Terraform modules", "path", req.InputPath)
+	dirs, err := uc.findModuleDirs(req.InputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan directories: %w", err)
+	}
+	if changed != nil {
+		dirs = slices.DeleteFunc(dirs, func(dir string) bool { return !inModules(changed, dir) })
+		uc.log.Info("Restricted to modules with changed Terraform files", "since", req.Since, "modules", len(dirs))
+	}
+
+	total := &OrganizeFilesResponse{
+		OutputDir: req.InputPath,
+		WasDryRun: req.DryRun,
+	}
+	// Modules are planned concurrently but written one at a time, in order
+	stop := make(chan struct{})
+	defer close(stop)
+	plans, release := uc.planModules(ctx, req, dirs, stop)
+	for i := range dirs {
+		if err := ctx.Err(); err != nil {
+			return nil, uc.interrupted(req, dirs[:i], dirs[i:], err)
+		}
+		var planned plannedModule
+		select {
+		case planned = <-plans[i]:
+		case <-ctx.Done():
+			// Planning stops starting modules once ctx is canceled
+			return nil, uc.interrupted(req, dirs[:i], dirs[i:], ctx.Err())
+		}
+		planned.flush()
+		if isCanceled(ctx, planned.err) {
+			return nil, uc.interrupted(req, dirs[:i], dirs[i:], planned.err)
+		}
+		if planned.err != nil {
+			return nil, planned.err
+		}
+		resp, err := uc.applyModule(ctx, planned.req, planned.plan)
+		if isCanceled(ctx, err) {
+			return nil, uc.interrupted(req, dirs[:i], dirs[i:], err)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("module %s: %w", dirs[i], err)
+		}
+		release()
+
+		total.ProcessedFiles += resp.ProcessedFiles
+		total.TotalBlocks += resp.TotalBlocks
+		total.FileGroups += resp.FileGroups
+		total.StaleFiles = append(total.StaleFiles, resp.StaleFiles...)
+		total.Groups = append(total.Groups, resp.Groups...)
+		total.Operations = append(total.Operations, resp.Operations...)
+	}
+
+	uc.log.Info("Processed Terraform files", "files", total.ProcessedFiles, "blocks", total.TotalBlocks, "directories", len(dirs))
+	return total, nil
+}
+
+// executeModule organizes a single file, the .tf files directly inside one
+// directory, or in recursive mode those of its subdirectories too.
+func (uc *OrganizeFilesUsecase) executeModule(ctx context.Context, req *OrganizeFilesRequest, stat os.FileInfo) (*OrganizeFilesResponse, error) {
+	plan, err := uc.planModule(ctx, req, stat)
+	if err != nil {
+		return nil, err
+	}
+	return uc.applyModule(ctx, req, plan)
+}
+
+// modulePlan holds the outcome of the steps of organizing a module that only
+// read files, so that modules can be planned concurrently.
+type modulePlan struct {
+	stat        os.FileInfo
+	sources     string // digest of the source files before they were read
+	outputDir   string
+	cfg         *config.Config
+	parsedFiles *types.ParsedFiles
+	groups      []*types.BlockGroup
+	license     string
+}
+
+// planModule loads the configuration of a module, parses its files and
+// groups their blocks without changing any file.
+func (uc *OrganizeFilesUsecase) planModule(ctx context.Context, req *OrganizeFilesRequest, stat os.FileInfo) (*modulePlan, error) {
+	// 1. Prepare: input validation and config loading
+	inputDir := req.InputPath
+	if !stat.IsDir() {
+		inputDir = filepath.Dir(req.InputPath)
+	}
+
 	outputDir := req.OutputDir
 	if outputDir == "" {
-		if stat.IsDir() {
-			outputDir = req.InputPath
-		} else {
-			outputDir = filepath.Dir(req.InputPath)
-		}
+		outputDir = inputDir
 	}
 
-	cfg, err := uc.configLoader.LoadConfig(req.ConfigFile)
+	cfg, err := uc.configLoader.LoadConfig(req.ConfigFile, inputDir)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
 	// 2. Parse: extract blocks from files
-	parsedFiles, err := uc.parseInput(req.InputPath, stat, req.Recursive)
+	sources, err := uc.sourceDigest(req, stat)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input: %w", err)
+	}
+	parsedFiles, err := uc.parseInput(ctx, req.InputPath, stat, req.Recursive)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse input: %w", err)
 	}
 
+	plan := &modulePlan{stat: stat, sources: sources, outputDir: outputDir, cfg: cfg, parsedFiles: parsedFiles}
 	if parsedFiles.TotalBlocks() == 0 {
-		fmt.Println("No Terraform blocks found to organize")
+		return plan, nil
+	}
+
+	plan.license = uc.licenseHeader(cfg, parsedFiles)
+
+	// 3. Group: organize blocks by type and config
+	plan.groups, err = uc.getSplitter(cfg).GroupBlocks(parsedFiles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to group blocks: %w", err)
+	}
+	return plan, nil
+}
+
+// applyModule writes the files of a planned module atomically: if writing
+// fails or ctx is canceled before every file is written, the changes made to
+// the module are rolled back. Once the files are written, the source files
+// are cleaned up whatever the state of ctx. In git mode, where moves cannot be
+// rolled back, a module that started is always finished. The output
+// directory is locked until the module is cleaned up or rolled back, and the
+// module is planned again if its source files changed before the lock was
+// taken.
+func (uc *OrganizeFilesUsecase) applyModule(ctx context.Context, req *OrganizeFilesRequest, plan *modulePlan) (*OrganizeFilesResponse, error) {
+	if req.DryRun {
+		return uc.writeModule(ctx, req, plan)
+	}
+	unlock, err := uc.lockOutputDir(plan.outputDir)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	sources, err := uc.sourceDigest(req, plan.stat)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input: %w", err)
+	}
+	if sources != plan.sources {
+		uc.log.Info("Source files changed since they were read, organizing them again", "path", req.InputPath)
+		if plan, err = uc.planModule(ctx, req, plan.stat); err != nil {
+			return nil, err
+		}
+	}
+	if req.Git {
+		return uc.writeModule(context.WithoutCancel(ctx), req, plan)
+	}
+
+	j := newJournal(uc.fs)
+	journaled := *uc
+	journaled.fs = j
+	resp, err := journaled.writeModule(ctx, req, plan)
+	if err != nil {
+		rollbackErr := j.rollback()
+		if rollbackErr == nil {
+			uc.log.Warn("Rolled back changes to module", "path", req.InputPath)
+		}
+		return nil, &rollbackError{err: err, rollbackErr: rollbackErr}
+	}
+	return resp, nil
+}
+
+// writeModule writes the files of a planned module and cleans up its source
+// files.
+func (uc *OrganizeFilesUsecase) writeModule(ctx context.Context, req *OrganizeFilesRequest, plan *modulePlan) (*OrganizeFilesResponse, error) {
+	stat, outputDir, cfg, parsedFiles, groups := plan.stat, plan.outputDir, plan.cfg, plan.parsedFiles, plan.groups
+	if parsedFiles.TotalBlocks() == 0 {
+		uc.log.Info("No Terraform blocks found to organize")
 		return &OrganizeFilesResponse{
 			ProcessedFiles: len(parsedFiles.Files),
 			TotalBlocks:    0,
@@ -142,27 +428,46 @@ func (uc *OrganizeFilesUsecase) Execute(req *OrganizeFilesRequest) (*OrganizeFil
 			WasDryRun:      req.DryRun,
 		}, nil
 	}
+	uc.log.Info("Organized blocks into file groups", "groups", len(groups))
 
-	// 3. Group: organize blocks by type and config
-	groups, err := uc.getSplitter(cfg).GroupBlocks(parsedFiles)
+	// 4. Move: in git mode, move source files to their main output file first
+	filesToRemove := uc.getFilesToRemove(parsedFiles.FileNames(), groups, cfg)
+	operations, err := uc.gitMoveSources(req, stat, outputDir, groups, filesToRemove)
 	if err != nil {
-		return nil, fmt.Errorf("failed to group blocks: %w", err)
+		return nil, err
 	}
-	fmt.Printf("Organized into %d file groups\n", len(groups))
 
-	// 4. Write: output organized files
-	if err := uc.getWriter(outputDir, req.DryRun).WriteGroups(groups); err != nil {
+	// 5. Write: output organized files
+	w := uc.getWriter(outputDir, req, uc.writerOptions(req, stat, cfg, parsedFiles, plan.license))
+	if err := w.WriteGroups(ctx, groups); err != nil {
 		return nil, fmt.Errorf("failed to write files: %w", err)
 	}
+	if reporter, ok := w.(interface{ Operations() []types.FileOperation }); ok {
+		operations = append(operations, reporter.Operations()...)
+	}
 
-	// 5. Cleanup: handle source files if needed
-	filesToRemove := uc.getFilesToRemove(parsedFiles.FileNames(), groups, cfg)
-	if err := uc.handleSourceFileCleanup(req, stat, outputDir, filesToRemove); err != nil {
+	// 6. Cleanup: handle source files if needed; moved files are gone already
+	remaining := unmovedFiles(filesToRemove, operations)
+	cleanup, err := uc.handleSourceFileCleanup(req, stat, outputDir, remaining)
+	if err != nil {
+		return nil, err
+	}
+	operations = append(operations, cleanup...)
+
+	// 7. Prune: remove files generated by a previous run that are no longer produced
+	staleFiles, err := uc.pruneStaleFiles(req, stat, outputDir, groups, filesToRemove)
+	if err != nil {
 		return nil, err
 	}
+	operations = append(operations, removalOperations(staleFiles, outputDir, req.Backup)...)
 
-	// 6. Display results
-	uc.displayResults(req, stat, outputDir, filesToRemove)
+	// 8. Stage: in git mode, stage every file changed
+	if err := uc.gitStage(req, stat, outputDir, operations); err != nil {
+		return nil, err
+	}
+
+	// 9. Display results
+	uc.displayResults(req, stat, outputDir, remaining, staleFiles)
 
 	return &OrganizeFilesResponse{
 		ProcessedFiles: len(parsedFiles.Files),
@@ -170,9 +475,145 @@ func (uc *OrganizeFilesUsecase) Execute(req *OrganizeFilesRequest) (*OrganizeFil
 		FileGroups:     len(groups),
 		OutputDir:      outputDir,
 		WasDryRun:      req.DryRun,
+		StaleFiles:     staleFiles,
+		Groups:         groups,
+		Operations:     operations,
 	}, nil
 }
 
+// pruneStaleFiles removes files recorded in the output directory's manifest by
+// a previous run that this run no longer generates, then records the files
+// generated now. Stale files edited since they were generated are kept. When
+// the input is a single file, which covers only part of the output directory,
+// a file is stale only if this run wrote every block recorded for it elsewhere.
+func (uc *OrganizeFilesUsecase) pruneStaleFiles(req *OrganizeFilesRequest, stat os.FileInfo, outputDir string, groups []*types.BlockGroup, filesToRemove []string) ([]string, error) {
+	previous, err := manifest.Load(uc.fs, outputDir)
+	if err != nil {
+		return nil, err
+	}
+	partial := !stat.IsDir()
+	written := make(map[string]bool) // addresses, and content hashes of blocks without labels
+	for _, group := range groups {
+		for _, block := range group.Blocks {
+			if len(block.Labels) == 0 {
+				written[block.ContentHash()] = true
+				continue
+			}
+			written[block.Address()] = true
+		}
+	}
+
+	handled := make(map[string]bool)
+	for _, file := range filesToRemove {
+		handled[filepath.Clean(file)] = true
+	}
+
+	generatedFiles := uc.buildGeneratedFilesMap(groups)
+	var staleFiles []string
+	for _, fileName := range previous.Stale(generatedFiles) {
+		path := filepath.Join(outputDir, fileName)
+		if handled[path] {
+			continue // already removed as a reorganized source file
+		}
+		if partial && !allWritten(previous.Files[fileName], written) {
+			continue // holds blocks of files this run did not read
+		}
+		content, err := uc.fs.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stale file %s: %w", path, err)
+		}
+		if !previous.Unchanged(fileName, content) {
+			uc.log.Warn("keeping stale generated file that has been modified since it was generated", "path", path)
+			continue
+		}
+		unmanaged, err := writer.UnmanagedBlocks(path, content, func(address, hash string) bool {
+			if hash != "" {
+				return previous.WroteUnlabeled(fileName, hash)
+			}
+			return previous.Wrote(fileName, address)
+		})
+		if err != nil || len(unmanaged) > 0 {
+			uc.log.Warn("keeping stale generated file that holds blocks it did not generate", "path", path)
+			continue
+		}
+		staleFiles = append(staleFiles, path)
+	}
+
+	if req.DryRun {
+		for _, path := range staleFiles {
+			uc.log.Info("Would remove stale generated file", "path", path)
+		}
+		return staleFiles, nil
+	}
+
+	if len(staleFiles) > 0 {
+		if req.Backup {
+			err = uc.backupSourceFiles(staleFiles, outputDir)
+		} else {
+			err = uc.removeSourceFiles(staleFiles)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to prune stale generated files: %w", err)
+		}
+	}
+
+	next := manifest.New()
+	if partial {
+		// Files of the rest of the output directory are still managed
+		for fileName, entry := range previous.Files {
+			if !slices.Contains(staleFiles, filepath.Join(outputDir, fileName)) {
+				next.Files[fileName] = entry
+			}
+		}
+	}
+	for _, group := range groups {
+		content, err := uc.fs.ReadFile(filepath.Join(outputDir, group.FileName))
+		if err != nil {
+			continue // not written, e.g. by a custom writer
+		}
+		addresses := make([]string, 0, len(group.Blocks))
+		var unlabeled []string
+		for _, block := range group.Blocks {
+			addresses = append(addresses, block.Address())
+			if len(block.Labels) == 0 {
+				unlabeled = append(unlabeled, block.ContentHash())
+			}
+		}
+		next.Record(group.FileName, content, addresses)
+		next.RecordUnlabeled(group.FileName, unlabeled)
+	}
+	if len(next.Files) == 0 && len(previous.Files) == 0 {
+		return staleFiles, nil
+	}
+	if err := next.Save(uc.fs, outputDir); err != nil {
+		return nil, err
+	}
+	return staleFiles, nil
+}
+
+// allWritten reports whether every block recorded in entry is in written:
+// blocks with labels by address, blocks without labels by content hash.
+func allWritten(entry manifest.FileEntry, written map[string]bool) bool {
+	for _, address := range entry.Blocks {
+		if strings.Contains(address, ".") {
+			if !written[address] {
+				return false
+			}
+		} else if len(entry.Unlabeled) == 0 {
+			return false // recorded without content hashes
+		}
+	}
+	for _, hash := range entry.Unlabeled {
+		if !written[hash] {
+			return false
+		}
+	}
+	return true
+}
+
 func (uc *OrganizeFilesUsecase) getSplitter(cfg *config.Config) SplitterInterface {
 	if uc.splitter != nil {
 		return uc.splitter
@@ -180,141 +621,262 @@ func (uc *OrganizeFilesUsecase) getSplitter(cfg *config.Config) SplitterInterfac
 	return splitter.NewWithConfig(cfg)
 }
 
-func (uc *OrganizeFilesUsecase) getWriter(outputDir string, dryRun bool) WriterInterface {
+func (uc *OrganizeFilesUsecase) getWriter(outputDir string, req *OrganizeFilesRequest, options writer.Options) WriterInterface {
 	if uc.writer != nil {
 		return uc.writer
 	}
-	return writer.New(outputDir, dryRun)
+	return writer.NewWithOptions(outputDir, req.DryRun, options)
 }
 
-func (uc *OrganizeFilesUsecase) handleSourceFileCleanup(req *OrganizeFilesRequest, stat os.FileInfo, outputDir string, filesToRemove []string) error {
+// writerOptions combines the request flags and output settings with the layout
+// and license header of the source files. A single input file covers only part
+// of the output directory, so blocks it does not hold are kept.
+func (uc *OrganizeFilesUsecase) writerOptions(req *OrganizeFilesRequest, stat os.FileInfo, cfg *config.Config, parsedFiles *types.ParsedFiles, license string) writer.Options {
+	options := writer.Options{
+		Force:           req.Force,
+		Logger:          uc.log,
+		FS:              uc.fs,
+		Format:          cfg.Output.Apply(fileformat.Dominant(parsedFiles.Formats())),
+		License:         license,
+		NormalizeBlocks: req.NormalizeBlocks,
+		KeepPrevious:    !stat.IsDir(),
+	}
+	if cfg.Output != nil {
+		options.Header = cfg.Output.Header
+		options.SectionSeparators = cfg.Output.SectionSeparators != nil && *cfg.Output.SectionSeparators
+	}
+	return options
+}
+
+// licenseHeader returns the file-level header shared by most source files when
+// license headers are enabled, and detaches it from the first block of those
+// files so it is written once at the top of every generated file. Files with a
+// different header keep it with their first block.
+func (uc *OrganizeFilesUsecase) licenseHeader(cfg *config.Config, parsedFiles *types.ParsedFiles) string {
+	enabled, pattern := cfg.Output.LicenseHeaderPattern()
+	if !enabled {
+		return ""
+	}
+
+	counts := make(map[string]int)
+	var license string
+	for _, file := range parsedFiles.Files {
+		if file.Header == "" || (pattern != nil && !pattern.MatchString(file.Header)) {
+			continue
+		}
+		counts[file.Header]++
+		if counts[file.Header] > counts[license] {
+			license = file.Header
+		}
+	}
+	if license == "" {
+		return ""
+	}
+
+	for _, file := range parsedFiles.Files {
+		if file.Header == license {
+			parser.SplitFileHeader(file, nil)
+		} else if counts[file.Header] > 0 {
+			uc.log.Warn("file has a different file header; keeping it with its first block", "path", file.FileName)
+		}
+	}
+	return license
+}
+
+// handleSourceFileCleanup removes or backs up reorganized source files when
+// the output directory is the input directory, and returns the operations
+// performed, or planned in dry-run mode.
+func (uc *OrganizeFilesUsecase) handleSourceFileCleanup(req *OrganizeFilesRequest, stat os.FileInfo, outputDir string, filesToRemove []string) ([]types.FileOperation, error) {
 	inputDir := req.InputPath
 	if !stat.IsDir() {
 		inputDir = filepath.Dir(req.InputPath)
 	}
 	sameDirectory := (outputDir == inputDir)
 
-	shouldProcessSourceFiles := !req.DryRun && len(filesToRemove) > 0 && sameDirectory
+	if len(filesToRemove) == 0 || !sameDirectory {
+		return nil, nil
+	}
 
-	if shouldProcessSourceFiles {
+	if !req.DryRun {
 		if req.Backup {
 			if err := uc.backupSourceFiles(filesToRemove, outputDir); err != nil {
-				return fmt.Errorf("failed to backup source files: %w", err)
+				return nil, fmt.Errorf("failed to backup source files: %w", err)
 			}
 		} else {
 			if err := uc.removeSourceFiles(filesToRemove); err != nil {
-				return fmt.Errorf("failed to remove source files: %w", err)
+				return nil, fmt.Errorf("failed to remove source files: %w", err)
 			}
 		}
 	}
 
-	return nil
+	return removalOperations(filesToRemove, outputDir, req.Backup), nil
 }
 
-func (uc *OrganizeFilesUsecase) displayResults(req *OrganizeFilesRequest, stat os.FileInfo, outputDir string, filesToRemove []string) {
+// removalOperations describes removing paths, or moving them to the backup
+// directory of outputDir when backup is set.
+func removalOperations(paths []string, outputDir string, backup bool) []types.FileOperation {
+	operations := make([]types.FileOperation, 0, len(paths))
+	for _, path := range paths {
+		if backup {
+			operations = append(operations, types.FileOperation{
+				Action: types.ActionBackup,
+				Path:   path,
+				Target: filepath.Join(outputDir, backupDirName, filepath.Base(path)),
+			})
+		} else {
+			operations = append(operations, types.FileOperation{Action: types.ActionRemove, Path: path})
+		}
+	}
+	return operations
+}
+
+func (uc *OrganizeFilesUsecase) displayResults(req *OrganizeFilesRequest, stat os.FileInfo, outputDir string, filesToRemove, staleFiles []string) {
 	inputDir := req.InputPath
 	if !stat.IsDir() {
 		inputDir = filepath.Dir(req.InputPath)
 	}
 	sameDirectory := (outputDir == inputDir)
 	shouldProcessSourceFiles := !req.DryRun && len(filesToRemove) > 0 && sameDirectory
+	removed := len(staleFiles)
+	if shouldProcessSourceFiles {
+		removed += len(filesToRemove)
+	}
 
 	if req.DryRun {
 		if sameDirectory && len(filesToRemove) > 0 {
 			if req.Backup {
-				fmt.Println("Plan completed. Use 'run --backup' to actually create files and backup source files.")
+				uc.log.Info("Plan completed. Use 'run --backup' to actually create files and backup source files.")
 			} else {
-				fmt.Println("Plan completed. Use 'run' to actually create files and remove source files.")
+				uc.log.Info("Plan completed. Use 'run' to actually create files and remove source files.")
 			}
 		} else {
-			fmt.Println("Plan completed. Use 'run' to actually create files.")
+			uc.log.Info("Plan completed. Use 'run' to actually create files.")
 		}
 	} else {
-		if shouldProcessSourceFiles {
+		if removed > 0 {
 			if req.Backup {
-				fmt.Printf("Successfully organized Terraform files into: %s (backed up %d source files)\n", outputDir, len(filesToRemove))
+				uc.log.Info("Successfully organized Terraform files", "output_dir", outputDir, "backed_up", removed)
 			} else {
-				fmt.Printf("Successfully organized Terraform files into: %s (removed %d source files)\n", outputDir, len(filesToRemove))
+				uc.log.Info("Successfully organized Terraform files", "output_dir", outputDir, "removed", removed)
 			}
 		} else {
-			fmt.Printf("Successfully organized Terraform files into: %s\n", outputDir)
+			uc.log.Info("Successfully organized Terraform files", "output_dir", outputDir)
 		}
 	}
 }
 
-func (uc *OrganizeFilesUsecase) parseInput(inputPath string, stat os.FileInfo, recursive bool) (*types.ParsedFiles, error) {
+func (uc *OrganizeFilesUsecase) parseInput(ctx context.Context, inputPath string, stat os.FileInfo, recursive bool) (*types.ParsedFiles, error) {
 	if stat.IsDir() {
 		if recursive {
-			fmt.Printf("Scanning directory recursively for Terraform files: %s\n", inputPath)
+			uc.log.Info("Scanning directory recursively for Terraform files", "path", inputPath)
 		} else {
-			fmt.Printf("Scanning directory for Terraform files: %s\n", inputPath)
+			uc.log.Info("Scanning directory for Terraform files", "path", inputPath)
 		}
-		parsedFiles, err := uc.parseDirectory(inputPath, recursive)
+		parsedFiles, err := uc.parseDirectory(ctx, inputPath, recursive)
 		if err != nil {
 			return nil, err
 		}
-		fmt.Printf("Found %d .tf files with %d total blocks\n", len(parsedFiles.Files), parsedFiles.TotalBlocks())
+		uc.log.Info("Found Terraform files", "files", len(parsedFiles.Files), "blocks", parsedFiles.TotalBlocks())
 		return parsedFiles, nil
 	} else {
-		fmt.Printf("Parsing Terraform file: %s\n", inputPath)
-		parsedFile, err := uc.parser.ParseFile(inputPath)
+		uc.log.Info("Parsing Terraform file", "path", inputPath)
+		parsedFile, err := uc.parser.ParseFile(ctx, inputPath)
 		if err != nil {
 			return nil, err
 		}
 		parsedFiles := &types.ParsedFiles{
 			Files: []*types.ParsedFile{parsedFile},
 		}
-		fmt.Printf("Found %d blocks\n", parsedFiles.TotalBlocks())
+		uc.log.Info("Found Terraform blocks", "blocks", parsedFiles.TotalBlocks())
 		return parsedFiles, nil
 	}
 }
 
-func (uc *OrganizeFilesUsecase) parseDirectory(dirPath string, recursive bool) (*types.ParsedFiles, error) {
-	if recursive {
-		return uc.parseDirectoryRecursive(dirPath)
+func (uc *OrganizeFilesUsecase) parseDirectory(ctx context.Context, dirPath string, recursive bool) (*types.ParsedFiles, error) {
+	paths, symlinks, err := uc.listDirectory(dirPath, recursive)
+	if err != nil {
+		return nil, err
 	}
-	return uc.parseDirectoryNonRecursive(dirPath)
+	for _, path := range symlinks {
+		uc.log.Warn("skipping symbolic link", "path", path)
+	}
+	return uc.parseFiles(ctx, paths)
 }
 
-func (uc *OrganizeFilesUsecase) parseDirectoryRecursive(dirPath string) (*types.ParsedFiles, error) {
-	parsedFiles := &types.ParsedFiles{
-		Files: make([]*types.ParsedFile, 0),
-	}
+// findModuleDirs returns every directory under root that directly contains
+// .tf files, in lexical order. Symbolic links and the tool's own backup
+// directories are skipped.
+func (uc *OrganizeFilesUsecase) findModuleDirs(root string) ([]string, error) {
+	var dirs []string
+	seen := make(map[string]bool)
 
-	err := filepath.Walk(dirPath, func(path string, info os.FileInfo, walkErr error) error {
+	err := uc.fs.Walk(root, func(path string, info os.FileInfo, walkErr error) error {
 		if walkErr != nil {
 			return walkErr
 		}
 
-		// Skip symbolic links for security
 		if info.Mode()&os.ModeSymlink != 0 {
-			fmt.Printf("Warning: skipping symbolic link: %s\n", path)
+			uc.log.Warn("skipping symbolic link", "path", path)
 			return nil
 		}
 
-		if !info.IsDir() && strings.HasSuffix(path, ".tf") {
-			parsedFile, parseErr := uc.parser.ParseFile(path)
-			if parseErr != nil {
-				fmt.Printf("Warning: failed to parse file %s: %v\n", path, parseErr)
-				return nil // Continue with warning only for file errors
+		if info.IsDir() {
+			if path != root && info.Name() == backupDirName {
+				return filepath.SkipDir
 			}
-			parsedFiles.Files = append(parsedFiles.Files, parsedFile)
+			return nil
 		}
 
+		if strings.HasSuffix(path, ".tf") {
+			dir := filepath.Dir(path)
+			if !seen[dir] {
+				seen[dir] = true
+				dirs = append(dirs, dir)
+			}
+		}
 		return nil
 	})
 
-	return parsedFiles, err
+	return dirs, err
 }
 
-func (uc *OrganizeFilesUsecase) parseDirectoryNonRecursive(dirPath string) (*types.ParsedFiles, error) {
-	parsedFiles := &types.ParsedFiles{
-		Files: make([]*types.ParsedFile, 0),
+// listDirectory returns the .tf files in dirPath, and in its subdirectories if
+// recursive, and the symbolic links skipped for security.
+func (uc *OrganizeFilesUsecase) listDirectory(dirPath string, recursive bool) (paths, symlinks []string, err error) {
+	if recursive {
+		return uc.listDirectoryRecursive(dirPath)
 	}
+	return uc.listDirectoryNonRecursive(dirPath)
+}
 
-	entries, err := os.ReadDir(dirPath)
+func (uc *OrganizeFilesUsecase) listDirectoryRecursive(dirPath string) (paths, symlinks []string, err error) {
+	err = uc.fs.Walk(dirPath, func(path string, info os.FileInfo, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+
+		// Skip symbolic links for security
+		if info.Mode()&os.ModeSymlink != 0 {
+			symlinks = append(symlinks, path)
+			return nil
+		}
+
+		if !info.IsDir() && strings.HasSuffix(path, ".tf") {
+			paths = append(paths, path)
+		}
+
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to read directory: %w", err)
+		return nil, nil, err
+	}
+	return paths, symlinks, nil
+}
+
+func (uc *OrganizeFilesUsecase) listDirectoryNonRecursive(dirPath string) (paths, symlinks []string, err error) {
+	entries, err := uc.fs.ReadDir(dirPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read directory: %w", err)
 	}
 
 	for _, entry := range entries {
@@ -330,24 +892,18 @@ func (uc *OrganizeFilesUsecase) parseDirectoryNonRecursive(dirPath string) (*typ
 
 		// Skip symbolic links for security
 		if info, infoErr := entry.Info(); infoErr == nil && info.Mode()&os.ModeSymlink != 0 {
-			fmt.Printf("Warning: skipping symbolic link: %s\n", path)
+			symlinks = append(symlinks, path)
 			continue
 		}
 
-		parsedFile, parseErr := uc.parser.ParseFile(path)
-		if parseErr != nil {
-			fmt.Printf("Warning: failed to parse file %s: %v\n", path, parseErr)
-			continue // Continue with warning only for file errors
-		}
-		parsedFiles.Files = append(parsedFiles.Files, parsedFile)
+		paths = append(paths, path)
 	}
-
-	return parsedFiles, nil
+	return paths, symlinks, nil
 }
 
 func (uc *OrganizeFilesUsecase) backupSourceFiles(sourceFiles []string, outputDir string) error {
-	backupDir := filepath.Join(outputDir, "backup")
-	if err := os.MkdirAll(backupDir, 0750); err != nil {
+	backupDir := filepath.Join(outputDir, backupDirName)
+	if err := uc.fs.MkdirAll(backupDir, 0750); err != nil {
 		return fmt.Errorf("failed to create backup directory: %w", err)
 	}
 
@@ -355,10 +911,10 @@ func (uc *OrganizeFilesUsecase) backupSourceFiles(sourceFiles []string, outputDi
 		fileName := filepath.Base(sourceFile)
 		backupPath := filepath.Join(backupDir, fileName)
 
-		if err := os.Rename(sourceFile, backupPath); err != nil {
+		if err := uc.fs.Rename(sourceFile, backupPath); err != nil {
 			return fmt.Errorf("failed to backup file %s: %w", sourceFile, err)
 		}
-		fmt.Printf("  Backed up: %s -> %s\n", sourceFile, backupPath)
+		uc.log.Info("Backed up file", "path", sourceFile, "backup", backupPath)
 	}
 
 	return nil
@@ -366,10 +922,10 @@ func (uc *OrganizeFilesUsecase) backupSourceFiles(sourceFiles []string, outputDi
 
 func (uc *OrganizeFilesUsecase) removeSourceFiles(sourceFiles []string) error {
 	for _, sourceFile := range sourceFiles {
-		if err := os.Remove(sourceFile); err != nil {
+		if err := uc.fs.Remove(sourceFile); err != nil {
 			return fmt.Errorf("failed to remove file %s: %w", sourceFile, err)
 		}
-		fmt.Printf("  Removed: %s\n", sourceFile)
+		uc.log.Info("Removed file", "path", sourceFile)
 	}
 
 	return nil
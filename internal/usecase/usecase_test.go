@@ -1,9 +1,22 @@
 package usecase_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
+	"github.com/tomoya-namekawa/tf-file-organize/internal/logging"
+	"github.com/tomoya-namekawa/tf-file-organize/internal/parser"
 	"github.com/tomoya-namekawa/tf-file-organize/internal/usecase"
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/filesystem"
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/types"
 )
 
 func TestDefaultConfigLoader_LoadConfig(t *testing.T) {
@@ -28,7 +41,7 @@ func TestDefaultConfigLoader_LoadConfig(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			cfg, err := loader.LoadConfig(tt.path)
+			cfg, err := loader.LoadConfig(tt.path, "")
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Expected error = %v, got error = %v for path = %s", tt.wantErr, err != nil, tt.path)
@@ -59,3 +72,129 @@ func TestNewOrganizeFilesUsecaseWithDeps(t *testing.T) {
 		t.Error("Expected usecase instance but got nil")
 	}
 }
+
+func TestOrganizeFilesUsecase_LogsWarnings(t *testing.T) {
+	fsys := filesystem.NewMemory()
+	files := map[string]string{
+		"main.tf":   "variable \"region\" {\n  type = string\n}\n",
+		"broken.tf": "resource \"aws_vpc\" {\n",
+	}
+	for name, content := range files {
+		if err := fsys.WriteFile(name, []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+	}
+
+	var logs bytes.Buffer
+	logger, err := logging.New(&logs, logging.Options{Level: slog.LevelWarn, Format: logging.FormatJSON})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	uc := usecase.NewOrganizeFilesUsecase()
+	uc.SetFileSystem(fsys)
+	uc.SetLogger(logger)
+	if _, err := uc.Execute(context.Background(), &usecase.OrganizeFilesRequest{InputPath: ".", DryRun: true}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	var event map[string]any
+	if err := json.Unmarshal(logs.Bytes(), &event); err != nil {
+		t.Fatalf("Expected a single JSON event, got %q: %v", logs.String(), err)
+	}
+	if event["level"] != "WARN" || event["msg"] != "failed to parse file" || event["path"] != "broken.tf" {
+		t.Errorf("Unexpected event %v", event)
+	}
+}
+
+func TestOrganizeFilesUsecase_InitConfigUsesFileSystem(t *testing.T) {
+	fsys := filesystem.NewMemory()
+	if err := fsys.WriteFile("network.tf", []byte("resource \"aws_vpc\" \"main\" {\n  cidr_block = \"10.0.0.0/16\"\n}\n"), 0600); err != nil {
+		t.Fatalf("Failed to create network.tf: %v", err)
+	}
+
+	uc := usecase.NewOrganizeFilesUsecase()
+	uc.SetFileSystem(fsys)
+	resp, err := uc.InitConfig(context.Background(), &usecase.InitConfigRequest{InputPath: "."})
+	if err != nil {
+		t.Fatalf("InitConfig failed: %v", err)
+	}
+
+	content, err := fsys.ReadFile(resp.ConfigFile)
+	if err != nil {
+		t.Fatalf("Expected config file in the file system: %v", err)
+	}
+	if !bytes.Equal(content, resp.Content) {
+		t.Errorf("Expected written config to match the response, got:\n%s", content)
+	}
+	if _, err := os.Stat(resp.ConfigFile); err == nil {
+		t.Errorf("Expected %s not to be written to disk", resp.ConfigFile)
+	}
+
+	_, err = uc.InitConfig(context.Background(), &usecase.InitConfigRequest{InputPath: "."})
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Expected existing config in the file system to be detected, got %v", err)
+	}
+}
+
+// changingParser parses files with the default parser and rewrites path the
+// first time it is parsed, like a concurrent run or an editor changing the
+// module between planning and writing.
+type changingParser struct {
+	usecase.ParserInterface
+	path    string
+	content string
+	once    sync.Once
+}
+
+func (p *changingParser) ParseFile(ctx context.Context, filename string) (*types.ParsedFile, error) {
+	file, err := p.ParserInterface.ParseFile(ctx, filename)
+	if filename == p.path {
+		p.once.Do(func() {
+			if writeErr := os.WriteFile(p.path, []byte(p.content), 0600); writeErr != nil {
+				panic(writeErr)
+			}
+		})
+	}
+	return file, err
+}
+
+func TestOrganizeFilesUsecase_ReplansChangedModule(t *testing.T) {
+	for _, recursive := range []bool{false, true} {
+		t.Run(fmt.Sprintf("recursive=%v", recursive), func(t *testing.T) {
+			root := t.TempDir()
+			for _, module := range []string{"a", "b"} {
+				if err := os.MkdirAll(filepath.Join(root, module), 0750); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(root, module, "main.tf"), []byte("resource \"aws_vpc\" \"main\" {}\n"), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			moduleDir := filepath.Join(root, "b")
+			p := &changingParser{
+				ParserInterface: parser.New(),
+				path:            filepath.Join(moduleDir, "main.tf"),
+				content:         "resource \"aws_vpc\" \"main\" {}\n\nresource \"aws_subnet\" \"added\" {}\n",
+			}
+			uc := usecase.NewOrganizeFilesUsecaseWithDeps(p, nil, nil, &usecase.DefaultConfigLoader{})
+			uc.SetLogger(nil)
+
+			input := moduleDir
+			if recursive {
+				input = root
+			}
+			if _, err := uc.Execute(context.Background(), &usecase.OrganizeFilesRequest{InputPath: input, Recursive: recursive, PerDirectory: recursive}); err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+
+			// The block added after the module was first read must not be lost
+			content, err := os.ReadFile(filepath.Join(moduleDir, "resource__aws_subnet.tf"))
+			if err != nil || !strings.Contains(string(content), `resource "aws_subnet" "added"`) {
+				t.Errorf("Expected the added block to be organized, got %q (%v)", content, err)
+			}
+			if _, err := os.Stat(filepath.Join(moduleDir, "main.tf")); !os.IsNotExist(err) {
+				t.Errorf("Expected main.tf to be removed, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+//go:build linux
+
+package watch
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// inotifyMask selects the events that change the content or set of files.
+const inotifyMask = syscall.IN_CLOSE_WRITE | syscall.IN_CREATE | syscall.IN_DELETE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_ONLYDIR
+
+// inotify reports changes through the Linux inotify API, with one watch per
+// directory.
+type inotify struct {
+	fd        int
+	file      *os.File
+	recursive bool
+	events    chan string
+	errors    chan error
+	done      chan struct{}
+
+	mu      sync.Mutex
+	watches map[int32]string // watch descriptor -> directory
+}
+
+func newNotifier(root string, recursive bool) (notifier, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize inotify: %w", err)
+	}
+	n := &inotify{
+		fd: fd,
+		// A non-blocking descriptor lets Close interrupt a pending read.
+		file:      os.NewFile(uintptr(fd), "inotify"),
+		recursive: recursive,
+		events:    make(chan string),
+		errors:    make(chan error, 1),
+		done:      make(chan struct{}),
+		watches:   make(map[int32]string),
+	}
+	if err := n.addTree(root); err != nil {
+		_ = n.file.Close()
+		return nil, err
+	}
+	go n.read()
+	return n, nil
+}
+
+func (n *inotify) Events() <-chan string { return n.events }
+
+func (n *inotify) Errors() <-chan error { return n.errors }
+
+func (n *inotify) Close() error {
+	close(n.done)
+	return n.file.Close()
+}
+
+// addTree watches dir and, in recursive mode, its subdirectories.
+func (n *inotify) addTree(dir string) error {
+	if !n.recursive {
+		return n.add(dir)
+	}
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		if path != dir && skipDir(entry.Name()) {
+			return filepath.SkipDir
+		}
+		return n.add(path)
+	})
+}
+
+func (n *inotify) add(dir string) error {
+	wd, err := syscall.InotifyAddWatch(n.fd, dir, inotifyMask)
+	if err != nil {
+		return fmt.Errorf("failed to watch %s: %w", dir, err)
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.watches[int32(wd)] = dir //nolint:gosec // watch descriptors are small positive ints
+	return nil
+}
+
+// read decodes inotify events until the notifier is closed.
+func (n *inotify) read() {
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		size, err := n.file.Read(buf)
+		if err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				n.fail(fmt.Errorf("failed to read inotify events: %w", err))
+			}
+			return
+		}
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= size; {
+			wd := int32(binary.NativeEndian.Uint32(buf[offset:])) //nolint:gosec // kernel layout of inotify_event
+			mask := binary.NativeEndian.Uint32(buf[offset+4:])
+			nameLen := int(binary.NativeEndian.Uint32(buf[offset+12:]))
+			start := offset + syscall.SizeofInotifyEvent
+			name := strings.TrimRight(string(buf[start:start+nameLen]), "\x00")
+			offset = start + nameLen
+
+			if !n.handle(wd, mask, name) {
+				return
+			}
+		}
+	}
+}
+
+// handle processes one event and reports whether reading should continue.
+func (n *inotify) handle(wd int32, mask uint32, name string) bool {
+	n.mu.Lock()
+	dir, ok := n.watches[wd]
+	if mask&syscall.IN_IGNORED != 0 {
+		delete(n.watches, wd)
+	}
+	n.mu.Unlock()
+	if !ok || name == "" {
+		return true
+	}
+
+	path := filepath.Join(dir, name)
+	if n.recursive && mask&syscall.IN_ISDIR != 0 && mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 && !skipDir(name) {
+		if err := n.addTree(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			n.fail(err)
+			return false
+		}
+	}
+
+	select {
+	case n.events <- path:
+		return true
+	case <-n.done:
+		return false
+	}
+}
+
+func (n *inotify) fail(err error) {
+	select {
+	case n.errors <- err:
+	default:
+	}
+}
@@ -0,0 +1,118 @@
+//go:build !linux
+
+package watch
+
+import (
+	"io/fs"
+	"path/filepath"
+	"time"
+)
+
+// pollInterval is how often the tree is scanned for changes.
+const pollInterval = 500 * time.Millisecond
+
+// fileState is what a scan compares to detect a changed file.
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// poller reports changes by periodically scanning the tree, on platforms
+// without an inotify implementation.
+type poller struct {
+	root      string
+	recursive bool
+	events    chan string
+	errors    chan error
+	done      chan struct{}
+}
+
+func newNotifier(root string, recursive bool) (notifier, error) {
+	p := &poller{
+		root:      root,
+		recursive: recursive,
+		events:    make(chan string),
+		errors:    make(chan error, 1),
+		done:      make(chan struct{}),
+	}
+	files, err := p.scan()
+	if err != nil {
+		return nil, err
+	}
+	go p.poll(files)
+	return p, nil
+}
+
+func (p *poller) Events() <-chan string { return p.events }
+
+func (p *poller) Errors() <-chan error { return p.errors }
+
+func (p *poller) Close() error {
+	close(p.done)
+	return nil
+}
+
+func (p *poller) poll(files map[string]fileState) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+
+		current, err := p.scan()
+		if err != nil {
+			select {
+			case p.errors <- err:
+			default:
+			}
+			return
+		}
+		var changed []string
+		for path, state := range current {
+			if previous, ok := files[path]; !ok || previous != state {
+				changed = append(changed, path)
+			}
+		}
+		for path := range files {
+			if _, ok := current[path]; !ok {
+				changed = append(changed, path)
+			}
+		}
+		files = current
+
+		for _, path := range changed {
+			select {
+			case p.events <- path:
+			case <-p.done:
+				return
+			}
+		}
+	}
+}
+
+// scan records the state of the files under the root.
+func (p *poller) scan() (map[string]fileState, error) {
+	files := make(map[string]fileState)
+	err := filepath.WalkDir(p.root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != p.root && (!p.recursive || skipDir(entry.Name())) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			// The file was removed during the scan
+			return nil
+		}
+		files[path] = fileState{size: info.Size(), modTime: info.ModTime()}
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,131 @@
+// Package watch reports changes to the Terraform files of a directory tree,
+// grouping bursts of edits into one batch.
+package watch
+
+import (
+	"context"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDebounce is how long the tree must stay quiet before a batch of
+// changes is reported.
+const DefaultDebounce = 300 * time.Millisecond
+
+// Options configures Watch.
+type Options struct {
+	Recursive bool          // Watch subdirectories too
+	Debounce  time.Duration // Quiet period before reporting (default: DefaultDebounce)
+}
+
+// notifier delivers the paths of changed files and directories.
+type notifier interface {
+	Events() <-chan string
+	Errors() <-chan error
+	Close() error
+}
+
+// Watch watches the .tf files under root until ctx is canceled and calls fn
+// with the sorted paths that changed, once the tree has been quiet for the
+// debounce period. Changes made while fn runs are reported in the next batch.
+// Hidden directories and backup directories are not watched.
+func Watch(ctx context.Context, root string, opts Options, fn func(paths []string)) error {
+	debounce := opts.Debounce
+	if debounce <= 0 {
+		debounce = DefaultDebounce
+	}
+
+	n, err := newNotifier(root, opts.Recursive)
+	if err != nil {
+		return err
+	}
+	defer n.Close()
+
+	pending := make(map[string]bool)
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-n.Errors():
+			return err
+		case path := <-n.Events():
+			if strings.HasSuffix(path, ".tf") {
+				pending[path] = true
+				timer.Reset(debounce)
+			}
+		case <-timer.C:
+			paths := make([]string, 0, len(pending))
+			for path := range pending {
+				paths = append(paths, path)
+			}
+			sort.Strings(paths)
+			clear(pending)
+			fn(paths)
+		}
+	}
+}
+
+// skipDir reports whether the directory name is not watched: hidden
+// directories such as .git and .terraform, and backup directories.
+func skipDir(name string) bool {
+	return strings.HasPrefix(name, ".") || name == "backup"
+}
+
+// Writes remembers the files written and removed by the tool, so the change
+// events they cause can be told apart from edits. It is safe for concurrent
+// use.
+type Writes struct {
+	mu    sync.Mutex
+	files map[string]*[sha256.Size]byte // nil for removed files
+}
+
+// NewWrites returns an empty Writes.
+func NewWrites() *Writes {
+	return &Writes{files: make(map[string]*[sha256.Size]byte)}
+}
+
+// Reset forgets every recorded write.
+func (w *Writes) Reset() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	clear(w.files)
+}
+
+// Written records that path was written with content.
+func (w *Writes) Written(path string, content []byte) {
+	sum := sha256.Sum256(content)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.files[filepath.Clean(path)] = &sum
+}
+
+// Removed records that path was removed.
+func (w *Writes) Removed(path string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.files[filepath.Clean(path)] = nil
+}
+
+// Own reports whether path is still as the tool left it, so a change event
+// for it was caused by the tool itself.
+func (w *Writes) Own(path string) bool {
+	w.mu.Lock()
+	sum, ok := w.files[filepath.Clean(path)]
+	w.mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	content, err := os.ReadFile(path) //nolint:gosec // path was written by the tool
+	if sum == nil {
+		return os.IsNotExist(err)
+	}
+	return err == nil && sha256.Sum256(content) == *sum
+}
@@ -0,0 +1,123 @@
+package watch_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/tomoya-namekawa/tf-file-organize/internal/watch"
+)
+
+// startWatch runs Watch on dir in the background and returns the channel
+// receiving its batches.
+func startWatch(t *testing.T, dir string, opts watch.Options) <-chan []string {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	batches := make(chan []string, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- watch.Watch(ctx, dir, opts, func(paths []string) { batches <- paths })
+	}()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Watch failed: %v", err)
+		}
+	})
+	// Give the watcher time to register before the test changes files
+	time.Sleep(600 * time.Millisecond)
+	return batches
+}
+
+func nextBatch(t *testing.T, batches <-chan []string) []string {
+	t.Helper()
+	select {
+	case paths := <-batches:
+		return paths
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for changes")
+		return nil
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func TestWatchDebouncesBursts(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "main.tf"), "")
+	batches := startWatch(t, dir, watch.Options{Debounce: 200 * time.Millisecond})
+
+	writeFile(t, filepath.Join(dir, "main.tf"), `resource "aws_vpc" "main" {}`)
+	writeFile(t, filepath.Join(dir, "variables.tf"), `variable "region" {}`)
+	writeFile(t, filepath.Join(dir, "README.md"), "not terraform")
+
+	expected := []string{filepath.Join(dir, "main.tf"), filepath.Join(dir, "variables.tf")}
+	if paths := nextBatch(t, batches); !slices.Equal(paths, expected) {
+		t.Errorf("Expected one batch %v, got %v", expected, paths)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "variables.tf")); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+	expected = []string{filepath.Join(dir, "variables.tf")}
+	if paths := nextBatch(t, batches); !slices.Equal(paths, expected) {
+		t.Errorf("Expected removal batch %v, got %v", expected, paths)
+	}
+}
+
+func TestWatchRecursive(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"modules/vpc", ".terraform", "backup"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0750); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+	batches := startWatch(t, dir, watch.Options{Recursive: true, Debounce: 200 * time.Millisecond})
+
+	writeFile(t, filepath.Join(dir, ".terraform", "ignored.tf"), "")
+	writeFile(t, filepath.Join(dir, "backup", "ignored.tf"), "")
+	writeFile(t, filepath.Join(dir, "modules", "vpc", "main.tf"), "")
+
+	expected := []string{filepath.Join(dir, "modules", "vpc", "main.tf")}
+	if paths := nextBatch(t, batches); !slices.Equal(paths, expected) {
+		t.Errorf("Expected %v, got %v", expected, paths)
+	}
+}
+
+func TestWrites(t *testing.T) {
+	dir := t.TempDir()
+	written := filepath.Join(dir, "network.tf")
+	removed := filepath.Join(dir, "main.tf")
+	writeFile(t, written, "content")
+
+	writes := watch.NewWrites()
+	writes.Written(written, []byte("content"))
+	writes.Removed(removed)
+
+	if !writes.Own(written) || !writes.Own(removed) {
+		t.Error("Expected files left as written to be reported as own writes")
+	}
+	if writes.Own(filepath.Join(dir, "other.tf")) {
+		t.Error("Expected unrecorded file not to be an own write")
+	}
+
+	writeFile(t, written, "edited")
+	writeFile(t, removed, "recreated")
+	if writes.Own(written) || writes.Own(removed) {
+		t.Error("Expected files edited after the run not to be own writes")
+	}
+
+	writeFile(t, written, "content")
+	writes.Reset()
+	if writes.Own(written) {
+		t.Error("Expected Reset to forget recorded writes")
+	}
+}
@@ -0,0 +1,117 @@
+package writer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+
+	"github.com/tomoya-namekawa/tf-file-organize/internal/fileformat"
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/types"
+)
+
+// UnmanagedBlocks returns the source text, including leading comments, of the
+// blocks in content that are not managed. managed receives the address of each
+// block and, for blocks without labels, which share their address with every
+// block of their type, its content hash; hash is empty for other blocks. It
+// fails when content holds anything that cannot be carried over block by block.
+func UnmanagedBlocks(filename string, content []byte, managed func(address, hash string) bool) ([]string, error) {
+	content = fileformat.Decode(content)
+	file, diags := hclsyntax.ParseConfig(content, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("existing content could not be parsed: %s", diags.Error())
+	}
+
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil, fmt.Errorf("existing content could not be parsed")
+	}
+	if len(body.Attributes) > 0 {
+		return nil, fmt.Errorf("existing content has top-level attributes")
+	}
+
+	var unmanaged []string
+	prevEnd := 0
+	for _, block := range body.Blocks {
+		end := block.CloseBraceRange.End.Byte
+		parsed := &types.Block{Type: block.Type, Labels: block.Labels}
+		var hash string
+		if len(block.Labels) == 0 {
+			parsed.RawBody = string(content[block.OpenBraceRange.End.Byte:block.CloseBraceRange.Start.Byte])
+			hash = parsed.ContentHash()
+		}
+		if !managed(parsed.Address(), hash) {
+			// Comments between the previous block and this one belong to this block
+			unmanaged = append(unmanaged, strings.TrimSpace(string(content[prevEnd:end])))
+		}
+		prevEnd = end
+	}
+	return unmanaged, nil
+}
+
+// mergeExisting appends blocks from the existing target file that this run
+// does not manage to the generated content.
+func (w *Writer) mergeExisting(filePath, fileName string, existing, generated []byte) ([]byte, error) {
+	unmanaged, err := UnmanagedBlocks(filePath, existing, w.managedIn(fileName))
+	if err != nil {
+		if !w.options.Force {
+			return nil, fmt.Errorf("refusing to overwrite %s: %w (use --force to overwrite)", filePath, err)
+		}
+		w.log.Warn("overwriting file whose content cannot be merged", "path", filePath, "error", err)
+		return generated, nil
+	}
+	if len(unmanaged) == 0 {
+		return generated, nil
+	}
+
+	w.log.Info("Preserved existing blocks not managed by this run", "path", filePath, "blocks", len(unmanaged))
+	return w.appendUnmanaged(generated, unmanaged), nil
+}
+
+// appendUnmanaged appends preserved blocks to the generated content.
+func (w *Writer) appendUnmanaged(generated []byte, unmanaged []string) []byte {
+	if len(unmanaged) == 0 {
+		return generated
+	}
+	for i, chunk := range unmanaged {
+		unmanaged[i] = w.stripGenerated(chunk)
+	}
+	merged := append(append([]byte{}, generated...), []byte("\n"+strings.Join(unmanaged, "\n\n")+"\n")...)
+	return hclwrite.Format(merged)
+}
+
+// managedIn returns whether a block in fileName belongs to this run: the
+// block is written by this run, or was written to fileName by a previous run
+// unless KeepPrevious is set. Blocks without labels are matched by content.
+func (w *Writer) managedIn(fileName string) func(address, hash string) bool {
+	return func(address, hash string) bool {
+		if hash != "" {
+			return w.ownedUnlabeled[hash] || (!w.options.KeepPrevious && w.previous.WroteUnlabeled(fileName, hash))
+		}
+		return w.owned[address] || (!w.options.KeepPrevious && w.previous.Wrote(fileName, address))
+	}
+}
+
+// planMerge reports how an existing target file would be merged in dry-run
+// mode and returns the content that would be written.
+func (w *Writer) planMerge(filePath, fileName string, generated []byte) []byte {
+	existing, err := w.fsys.ReadFile(filePath)
+	if err != nil {
+		return generated
+	}
+	unmanaged, err := UnmanagedBlocks(filePath, existing, w.managedIn(fileName))
+	switch {
+	case err != nil && w.options.Force:
+		w.log.Info("Would overwrite existing content", "path", filePath, "error", err)
+	case err != nil:
+		w.log.Warn("would refuse to overwrite existing content", "path", filePath, "error", err)
+	case len(unmanaged) > 0:
+		w.log.Info("Would preserve existing blocks", "path", filePath, "blocks", len(unmanaged))
+	}
+	if err != nil {
+		return generated
+	}
+	return w.appendUnmanaged(generated, unmanaged)
+}
@@ -0,0 +1,211 @@
+package writer_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tomoya-namekawa/tf-file-organize/internal/manifest"
+	"github.com/tomoya-namekawa/tf-file-organize/internal/parser"
+	"github.com/tomoya-namekawa/tf-file-organize/internal/writer"
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/filesystem"
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/types"
+)
+
+func TestWriteGroupsPreservesUnmanagedBlocks(t *testing.T) {
+	tmpDir := t.TempDir()
+	target := filepath.Join(tmpDir, "network.tf")
+	existing := `resource "aws_vpc" "main" {
+  cidr_block = "10.0.0.0/8"
+}
+
+# Managed by hand
+resource "aws_vpn_gateway" "legacy" {
+  vpc_id = "vpc-123"
+}
+`
+	if err := os.WriteFile(target, []byte(existing), 0600); err != nil {
+		t.Fatalf("Failed to create target file: %v", err)
+	}
+
+	block := parseHCLBlock(t, `
+resource "aws_vpc" "main" {
+  cidr_block = "10.0.0.0/16"
+}
+`)
+	groups := []*types.BlockGroup{createTestBlockGroup("network.tf", "resource", []*types.Block{block})}
+
+	if err := writer.New(tmpDir, false).WriteGroups(context.Background(), groups); err != nil {
+		t.Fatalf("WriteGroups failed: %v", err)
+	}
+
+	content, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatalf("Failed to read target file: %v", err)
+	}
+	got := string(content)
+	if !strings.Contains(got, `"10.0.0.0/16"`) || strings.Contains(got, `"10.0.0.0/8"`) {
+		t.Errorf("Expected managed block to be updated, got:\n%s", got)
+	}
+	if !strings.Contains(got, "# Managed by hand\nresource \"aws_vpn_gateway\" \"legacy\"") {
+		t.Errorf("Expected unmanaged block and its comment to be preserved, got:\n%s", got)
+	}
+
+	// A second run leaves the merged file unchanged
+	if err := writer.New(tmpDir, false).WriteGroups(context.Background(), groups); err != nil {
+		t.Fatalf("WriteGroups failed: %v", err)
+	}
+	again, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatalf("Failed to read target file: %v", err)
+	}
+	if string(again) != got {
+		t.Errorf("Expected merge to be idempotent, got:\n%s", again)
+	}
+}
+
+func TestWriteGroupsPreservesHandAddedUnlabeledBlocks(t *testing.T) {
+	parseLocals := func(t *testing.T, source string) *types.Block {
+		t.Helper()
+		srcPath := filepath.Join(t.TempDir(), "main.tf")
+		if err := os.WriteFile(srcPath, []byte(source), 0600); err != nil {
+			t.Fatalf("Failed to create source file: %v", err)
+		}
+		parsed, err := parser.New().ParseFile(context.Background(), srcPath)
+		if err != nil {
+			t.Fatalf("ParseFile failed: %v", err)
+		}
+		return parsed.Blocks[0]
+	}
+
+	tmpDir := t.TempDir()
+	target := filepath.Join(tmpDir, "locals.tf")
+	previous := parseLocals(t, "locals {\n  region = \"us-east-1\"\n}\n")
+	existing := `locals {
+  region = "us-east-1"
+}
+
+# Added by hand
+locals {
+  team = "platform"
+}
+`
+	if err := os.WriteFile(target, []byte(existing), 0600); err != nil {
+		t.Fatalf("Failed to create target file: %v", err)
+	}
+	m := manifest.New()
+	m.Record("locals.tf", []byte(existing), []string{"locals"})
+	m.RecordUnlabeled("locals.tf", []string{previous.ContentHash()})
+	if err := m.Save(filesystem.OS(), tmpDir); err != nil {
+		t.Fatalf("Failed to save manifest: %v", err)
+	}
+
+	block := parseLocals(t, "locals {\n  region = \"eu-west-1\"\n}\n")
+	groups := []*types.BlockGroup{createTestBlockGroup("locals.tf", "locals", []*types.Block{block})}
+
+	if err := writer.New(tmpDir, false).WriteGroups(context.Background(), groups); err != nil {
+		t.Fatalf("WriteGroups failed: %v", err)
+	}
+
+	content, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatalf("Failed to read target file: %v", err)
+	}
+	got := string(content)
+	if !strings.Contains(got, `"eu-west-1"`) || strings.Contains(got, `"us-east-1"`) {
+		t.Errorf("Expected generated locals block to be replaced, got:\n%s", got)
+	}
+	if !strings.Contains(got, "# Added by hand\nlocals {\n  team = \"platform\"\n}") {
+		t.Errorf("Expected hand-added locals block to be preserved, got:\n%s", got)
+	}
+
+	// A second run leaves the merged file unchanged
+	if err := writer.New(tmpDir, false).WriteGroups(context.Background(), groups); err != nil {
+		t.Fatalf("WriteGroups failed: %v", err)
+	}
+	again, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatalf("Failed to read target file: %v", err)
+	}
+	if string(again) != got {
+		t.Errorf("Expected merge to be idempotent, got:\n%s", again)
+	}
+}
+
+func TestWriteGroupsLogsUpdatedFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	write := func(cidr string) string {
+		t.Helper()
+		block := parseHCLBlock(t, "resource \"aws_vpc\" \"main\" {\n  cidr_block = \""+cidr+"\"\n}\n")
+		groups := []*types.BlockGroup{createTestBlockGroup("network.tf", "resource", []*types.Block{block})}
+		var logs bytes.Buffer
+		w := writer.NewWithOptions(tmpDir, false, writer.Options{Logger: slog.New(slog.NewTextHandler(&logs, nil))})
+		if err := w.WriteGroups(context.Background(), groups); err != nil {
+			t.Fatalf("WriteGroups failed: %v", err)
+		}
+		return logs.String()
+	}
+
+	if logs := write("10.0.0.0/16"); !strings.Contains(logs, `msg="Created file"`) {
+		t.Errorf("Expected new file to be reported as created, got:\n%s", logs)
+	}
+	if logs := write("10.1.0.0/16"); !strings.Contains(logs, `msg="Updated file"`) || strings.Contains(logs, "Created file") {
+		t.Errorf("Expected existing file to be reported as updated, got:\n%s", logs)
+	}
+}
+
+func TestWriteGroupsRefusesUnknownContent(t *testing.T) {
+	tmpDir := t.TempDir()
+	target := filepath.Join(tmpDir, "network.tf")
+	existing := "this is not { valid HCL\n"
+	if err := os.WriteFile(target, []byte(existing), 0600); err != nil {
+		t.Fatalf("Failed to create target file: %v", err)
+	}
+
+	block := parseHCLBlock(t, `
+resource "aws_vpc" "main" {
+  cidr_block = "10.0.0.0/16"
+}
+`)
+	groups := []*types.BlockGroup{createTestBlockGroup("network.tf", "resource", []*types.Block{block})}
+
+	err := writer.New(tmpDir, false).WriteGroups(context.Background(), groups)
+	if err == nil || !strings.Contains(err.Error(), "use --force") {
+		t.Fatalf("Expected refusal to overwrite, got %v", err)
+	}
+	if content, _ := os.ReadFile(target); string(content) != existing {
+		t.Errorf("Expected target file to be untouched, got:\n%s", content)
+	}
+
+	if err := writer.NewWithOptions(tmpDir, false, writer.Options{Force: true}).WriteGroups(context.Background(), groups); err != nil {
+		t.Fatalf("WriteGroups with Force failed: %v", err)
+	}
+	if content, _ := os.ReadFile(target); !strings.Contains(string(content), `resource "aws_vpc" "main"`) {
+		t.Errorf("Expected target file to be overwritten, got:\n%s", content)
+	}
+}
+
+func TestUnmanagedBlocks(t *testing.T) {
+	content := []byte(`variable "region" {}
+
+// kept
+variable "zone" {}
+`)
+	unmanaged, err := writer.UnmanagedBlocks("variables.tf", content, func(address, _ string) bool {
+		return address == "variable.region"
+	})
+	if err != nil {
+		t.Fatalf("UnmanagedBlocks failed: %v", err)
+	}
+	if len(unmanaged) != 1 || unmanaged[0] != "// kept\nvariable \"zone\" {}" {
+		t.Errorf("Unexpected unmanaged blocks: %q", unmanaged)
+	}
+
+	if _, err := writer.UnmanagedBlocks("main.tf", []byte("region = \"us-east-1\"\n"), func(string, string) bool { return false }); err == nil {
+		t.Error("Expected top-level attributes to be reported as unknown content")
+	}
+}
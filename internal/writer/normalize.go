@@ -0,0 +1,176 @@
+package writer
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// Categories of block body items in canonical order. An item's rank is its
+// category times categorySize plus its position within the category; items
+// with the same rank keep their source order. Categories are separated by a
+// blank line.
+const (
+	categoryMeta = iota
+	categoryArgument
+	categoryBlock
+	categoryLifecycle
+	categoryDependsOn
+
+	categorySize = 100
+)
+
+// Meta-arguments placed before regular arguments. Modules also lead with
+// their source and version.
+var (
+	metaArguments       = []string{"count", "for_each", "provider", "providers"}
+	moduleMetaArguments = []string{"source", "version", "count", "for_each", "providers"}
+	variableArguments   = []string{"description", "type", "default", "sensitive"}
+)
+
+// bodyItem is an attribute or nested block of a block body together with its
+// source text: the comments and blank lines before it and everything up to the
+// end of its last line, including a trailing comment.
+type bodyItem struct {
+	name    string
+	isBlock bool
+	text    string
+}
+
+// normalizeBody reorders the attributes and nested blocks of a raw block body
+// into canonical order. Comments move with the item they precede or trail.
+// Bodies that cannot be parsed, and block types without a canonical order,
+// are returned unchanged.
+func normalizeBody(blockType, rawBody string) string {
+	rank := rankFunc(blockType)
+	if rank == nil {
+		return rawBody
+	}
+
+	// Text on the line of the opening brace stays there
+	newline := strings.IndexByte(rawBody, '\n')
+	if newline < 0 {
+		return rawBody
+	}
+	head, content := rawBody[:newline+1], []byte(rawBody[newline+1:])
+
+	file, diags := hclsyntax.ParseConfig(content, "", hcl.InitialPos)
+	if diags.HasErrors() {
+		return rawBody
+	}
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return rawBody
+	}
+
+	items, trailer := splitBodyItems(content, body)
+	if len(items) < 2 {
+		return rawBody
+	}
+
+	sorted := make([]bodyItem, len(items))
+	copy(sorted, items)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return rank(sorted[i]) < rank(sorted[j])
+	})
+
+	var b strings.Builder
+	b.WriteString(head)
+	for i, item := range sorted {
+		text := item.text
+		if i == 0 || rank(item)/categorySize != rank(sorted[i-1])/categorySize {
+			// Each category starts after exactly one blank line
+			text = strings.TrimLeft(text, "\n")
+			if i > 0 {
+				b.WriteString("\n")
+			}
+		}
+		b.WriteString(text)
+	}
+	b.WriteString(trailer)
+	return b.String()
+}
+
+// splitBodyItems cuts content into the source text of each item of body, in
+// source order, and the text after the last item.
+func splitBodyItems(content []byte, body *hclsyntax.Body) ([]bodyItem, string) {
+	type span struct {
+		item       bodyItem
+		start, end int
+	}
+	var spans []span
+	for name, attr := range body.Attributes {
+		spans = append(spans, span{bodyItem{name: name}, attr.SrcRange.Start.Byte, attr.SrcRange.End.Byte})
+	}
+	for _, block := range body.Blocks {
+		spans = append(spans, span{bodyItem{name: block.Type, isBlock: true}, block.TypeRange.Start.Byte, block.CloseBraceRange.End.Byte})
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	items := make([]bodyItem, 0, len(spans))
+	prev := 0
+	for _, s := range spans {
+		// Extend to the end of the line to keep a trailing comment
+		end := len(content)
+		if i := strings.IndexByte(string(content[s.end:]), '\n'); i >= 0 {
+			end = s.end + i + 1
+		}
+		s.item.text = string(content[prev:end])
+		items = append(items, s.item)
+		prev = end
+	}
+	return items, string(content[prev:])
+}
+
+// rankFunc returns the ranking of body items for blockType, or nil if the
+// block type has no canonical order.
+func rankFunc(blockType string) func(bodyItem) int {
+	switch blockType {
+	case "resource", "data":
+		return func(item bodyItem) int { return genericRank(item, metaArguments) }
+	case "module":
+		return func(item bodyItem) int { return genericRank(item, moduleMetaArguments) }
+	case "variable":
+		return variableRank
+	default:
+		return nil
+	}
+}
+
+// genericRank orders meta-arguments, regular arguments, nested blocks, then
+// lifecycle and depends_on. Meta-arguments keep the order of meta.
+func genericRank(item bodyItem, meta []string) int {
+	switch {
+	case item.isBlock && item.name == "lifecycle":
+		return categoryLifecycle * categorySize
+	case item.isBlock:
+		return categoryBlock * categorySize
+	case item.name == "depends_on":
+		return categoryDependsOn * categorySize
+	}
+	for i, name := range meta {
+		if item.name == name {
+			return categoryMeta*categorySize + i
+		}
+	}
+	return categoryArgument * categorySize
+}
+
+// variableRank orders description, type, default, sensitive and other
+// arguments, then validation and other blocks.
+func variableRank(item bodyItem) int {
+	if item.isBlock {
+		if item.name == "validation" {
+			return categoryBlock * categorySize
+		}
+		return categoryBlock*categorySize + 1
+	}
+	for i, name := range variableArguments {
+		if item.name == name {
+			return categoryArgument*categorySize + i
+		}
+	}
+	return categoryArgument*categorySize + len(variableArguments)
+}
@@ -0,0 +1,131 @@
+package writer_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tomoya-namekawa/tf-file-organize/internal/parser"
+	"github.com/tomoya-namekawa/tf-file-organize/internal/writer"
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/types"
+)
+
+func TestWriteGroupsNormalizeBlocks(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{
+			name: "resource",
+			source: `resource "aws_instance" "web" {
+  depends_on = [aws_vpc.main]
+  ami        = "ami-123" # pinned
+
+  lifecycle {
+    create_before_destroy = true
+  }
+
+  # Root volume
+  root_block_device {
+    volume_size = 20
+  }
+  provider = aws.west
+  count    = 2
+}
+`,
+			expected: `resource "aws_instance" "web" {
+  count    = 2
+  provider = aws.west
+
+  ami = "ami-123" # pinned
+
+  # Root volume
+  root_block_device {
+    volume_size = 20
+  }
+
+  lifecycle {
+    create_before_destroy = true
+  }
+
+  depends_on = [aws_vpc.main]
+}`,
+		},
+		{
+			name: "variable",
+			source: `variable "region" {
+  validation {
+    condition     = length(var.region) > 0
+    error_message = "Region is required."
+  }
+  sensitive   = false
+  default     = "us-east-1"
+  type        = string
+  description = "AWS region"
+}
+`,
+			expected: `variable "region" {
+  description = "AWS region"
+  type        = string
+  default     = "us-east-1"
+  sensitive   = false
+
+  validation {
+    condition     = length(var.region) > 0
+    error_message = "Region is required."
+  }
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srcDir := t.TempDir()
+			srcPath := filepath.Join(srcDir, "main.tf")
+			if err := os.WriteFile(srcPath, []byte(tt.source), 0600); err != nil {
+				t.Fatalf("Failed to create source file: %v", err)
+			}
+			parsed, err := parser.New().ParseFile(context.Background(), srcPath)
+			if err != nil {
+				t.Fatalf("ParseFile failed: %v", err)
+			}
+
+			outDir := t.TempDir()
+			groups := []*types.BlockGroup{createTestBlockGroup("out.tf", parsed.Blocks[0].Type, parsed.Blocks)}
+			w := writer.NewWithOptions(outDir, false, writer.Options{NormalizeBlocks: true})
+			if err := w.WriteGroups(context.Background(), groups); err != nil {
+				t.Fatalf("WriteGroups failed: %v", err)
+			}
+
+			content, err := os.ReadFile(filepath.Join(outDir, "out.tf"))
+			if err != nil {
+				t.Fatalf("Failed to read output file: %v", err)
+			}
+			if string(content) != tt.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tt.expected, content)
+			}
+
+			// Normalizing the normalized output changes nothing
+			if err := os.WriteFile(srcPath, content, 0600); err != nil {
+				t.Fatalf("Failed to update source file: %v", err)
+			}
+			reparsed, err := parser.New().ParseFile(context.Background(), srcPath)
+			if err != nil {
+				t.Fatalf("ParseFile failed: %v", err)
+			}
+			groups = []*types.BlockGroup{createTestBlockGroup("again.tf", reparsed.Blocks[0].Type, reparsed.Blocks)}
+			if err := w.WriteGroups(context.Background(), groups); err != nil {
+				t.Fatalf("WriteGroups failed: %v", err)
+			}
+			again, err := os.ReadFile(filepath.Join(outDir, "again.tf"))
+			if err != nil {
+				t.Fatalf("Failed to read output file: %v", err)
+			}
+			if string(again) != string(content) {
+				t.Errorf("Expected normalization to be idempotent, got:\n%s", again)
+			}
+		})
+	}
+}
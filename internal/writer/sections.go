@@ -0,0 +1,156 @@
+package writer
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/types"
+)
+
+// separatorPattern matches section separators written by SectionSeparators.
+var separatorPattern = regexp.MustCompile(`^# --- \S+ ---$`)
+
+// headerLines renders a configured header as comment lines. Lines that are
+// not already comments are prefixed with "# ".
+func headerLines(header string) []string {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return nil
+	}
+	var lines []string
+	for line := range strings.SplitSeq(header, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			lines = append(lines, "#")
+		case strings.HasPrefix(line, "#"), strings.HasPrefix(line, "//"):
+			lines = append(lines, line)
+		default:
+			lines = append(lines, "# "+line)
+		}
+	}
+	return lines
+}
+
+// commentLines splits a comment into lines.
+func commentLines(comment string) []string {
+	if comment == "" {
+		return nil
+	}
+	return strings.Split(comment, "\n")
+}
+
+// sectionName returns the name shown in a block's section separator: the
+// resource type for resources, "data.<type>" for data sources and the block
+// type otherwise.
+func sectionName(block *types.Block) string {
+	switch {
+	case block.Type == "resource" && len(block.Labels) > 0:
+		return block.Labels[0]
+	case block.Type == "data" && len(block.Labels) > 0:
+		return "data." + block.Labels[0]
+	default:
+		return block.Type
+	}
+}
+
+// sections returns blocks with each section's blocks kept together, in order
+// of first appearance, and the number of sections.
+func sections(blocks []*types.Block) ([]*types.Block, int) {
+	var names []string
+	bySection := make(map[string][]*types.Block)
+	for _, block := range blocks {
+		name := sectionName(block)
+		if _, ok := bySection[name]; !ok {
+			names = append(names, name)
+		}
+		bySection[name] = append(bySection[name], block)
+	}
+
+	ordered := make([]*types.Block, 0, len(blocks))
+	for _, name := range names {
+		ordered = append(ordered, bySection[name]...)
+	}
+	return ordered, len(names)
+}
+
+// appendComment appends comment lines followed by a blank line. Empty lines
+// in comment are kept as blank lines.
+func appendComment(body *hclwrite.Body, lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+	for _, line := range lines {
+		if line == "" {
+			body.AppendNewline()
+			continue
+		}
+		body.AppendUnstructuredTokens(hclwrite.Tokens{
+			{Type: hclsyntax.TokenComment, Bytes: []byte(line)},
+			{Type: hclsyntax.TokenNewline, Bytes: []byte("\n")},
+		})
+	}
+	body.AppendNewline()
+}
+
+// stripGenerated removes the license header, configured header and section
+// separators from the comments at the start of text, so that reorganizing
+// generated files does not duplicate them.
+func (w *Writer) stripGenerated(text string) string {
+	lines := strings.Split(text, "\n")
+	end := 0
+	for end < len(lines) && isCommentOrBlank(lines[end]) {
+		end++
+	}
+
+	comments := make([]string, 0, end)
+	for _, line := range lines[:end] {
+		comments = append(comments, strings.TrimSpace(line))
+	}
+	stripped := false
+	for _, generated := range [][]string{w.license, w.header} {
+		start := 0
+		for start < len(comments) && comments[start] == "" {
+			start++
+		}
+		if n := len(generated); n > 0 && len(comments)-start >= n && slices.Equal(comments[start:start+n], generated) {
+			comments, stripped = comments[start+n:], true
+		}
+	}
+
+	var kept []string
+	for _, line := range comments {
+		if separatorPattern.MatchString(line) {
+			stripped = true
+			continue
+		}
+		kept = append(kept, line)
+	}
+	if !stripped {
+		return text
+	}
+
+	// Drop the blank lines left around the removed comments
+	var compact []string
+	for _, line := range kept {
+		if line == "" && (len(compact) == 0 || compact[len(compact)-1] == "") {
+			continue
+		}
+		compact = append(compact, line)
+	}
+	if end == len(lines) {
+		for len(compact) > 0 && compact[len(compact)-1] == "" {
+			compact = compact[:len(compact)-1]
+		}
+	}
+	return strings.Join(append(compact, lines[end:]...), "\n")
+}
+
+func isCommentOrBlank(line string) bool {
+	line = strings.TrimSpace(line)
+	return line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//")
+}
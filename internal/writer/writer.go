@@ -3,8 +3,9 @@
 package writer
 
 import (
+	"context"
 	"fmt"
-	"os"
+	"log/slog"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -15,6 +16,9 @@ import (
 	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/zclconf/go-cty/cty"
 
+	"github.com/tomoya-namekawa/tf-file-organize/internal/fileformat"
+	"github.com/tomoya-namekawa/tf-file-organize/internal/manifest"
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/filesystem"
 	"github.com/tomoya-namekawa/tf-file-organize/pkg/types"
 )
 
@@ -22,29 +26,113 @@ var emptyBlockSchema = &hcl.BodySchema{
 	Blocks: []hcl.BlockHeaderSchema{},
 }
 
+// Options configures optional Writer behavior.
+type Options struct {
+	// Force overwrites existing target files whose content cannot be merged.
+	Force bool
+	// Format sets the line endings, byte order mark and permissions of
+	// written files. A zero Mode uses fileformat.DefaultMode.
+	Format types.FileFormat
+	// License is a file-level comment, such as a license banner, written
+	// verbatim at the top of every file, before Header.
+	License string
+	// Header is a comment written at the top of every file.
+	Header string
+	// SectionSeparators adds a "# --- <type> ---" comment before each block
+	// type in files holding several types.
+	SectionSeparators bool
+	// NormalizeBlocks reorders attributes and nested blocks inside resource,
+	// data, module and variable blocks into canonical order.
+	NormalizeBlocks bool
+	// KeepPrevious keeps the blocks previous runs wrote to an existing target
+	// file unless this run writes them, for input that covers only part of
+	// the output directory, such as a single file.
+	KeepPrevious bool
+	// Logger receives progress and warning events. Nil means slog.Default().
+	Logger *slog.Logger
+	// FS is the file system files are written to. Nil means the OS.
+	FS filesystem.FileSystem
+}
+
 // Writer handles writing grouped blocks to output files.
 type Writer struct {
 	outputDir string
 	dryRun    bool
+	options   Options
+	log       *slog.Logger
+	fsys      filesystem.FileSystem
+	license   []string
+	header    []string
+
+	// Files written, or planned in dry-run mode, by WriteGroups
+	operations []types.FileOperation
+
+	// Blocks written by the current WriteGroups call, by address and, for
+	// blocks without labels, by content hash, and by previous runs
+	owned          map[string]bool
+	ownedUnlabeled map[string]bool
+	previous       *manifest.Manifest
 }
 
 // New creates a new Writer with default settings.
 func New(outputDir string, dryRun bool) *Writer {
+	return NewWithOptions(outputDir, dryRun, Options{})
+}
+
+// NewWithOptions creates a new Writer with the given options.
+func NewWithOptions(outputDir string, dryRun bool, options Options) *Writer {
+	logger := options.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	fsys := options.FS
+	if fsys == nil {
+		fsys = filesystem.OS()
+	}
 	return &Writer{
+		log:       logger,
+		fsys:      fsys,
 		outputDir: outputDir,
 		dryRun:    dryRun,
+		options:   options,
+		license:   commentLines(options.License),
+		header:    headerLines(options.Header),
 	}
 }
 
 // WriteGroups writes all block groups to their respective output files.
-func (w *Writer) WriteGroups(groups []*types.BlockGroup) error {
+// Existing target files are merged: blocks this run does not manage are kept.
+// It stops before the next file once ctx is canceled, leaving the files
+// written so far; Operations lists them.
+func (w *Writer) WriteGroups(ctx context.Context, groups []*types.BlockGroup) error {
 	if !w.dryRun {
-		if err := os.MkdirAll(w.outputDir, 0750); err != nil {
+		if err := w.fsys.MkdirAll(w.outputDir, 0750); err != nil {
 			return fmt.Errorf("failed to create output directory: %w", err)
 		}
 	}
 
+	previous, err := manifest.Load(w.fsys, w.outputDir)
+	if err != nil {
+		return err
+	}
+	w.previous = previous
+	w.operations = nil
+	w.owned = make(map[string]bool)
+	w.ownedUnlabeled = make(map[string]bool)
 	for _, group := range groups {
+		for _, block := range group.Blocks {
+			if len(block.Labels) == 0 {
+				w.ownedUnlabeled[block.ContentHash()] = true
+				continue
+			}
+			w.owned[block.Address()] = true
+		}
+	}
+
+	for _, group := range groups {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if err := w.writeGroup(group); err != nil {
 			return fmt.Errorf("failed to write group %s: %w", group.FileName, err)
 		}
@@ -83,39 +171,55 @@ func normalizeContent(content []byte) string {
 func (w *Writer) writeGroup(group *types.BlockGroup) error {
 	filePath := filepath.Join(w.outputDir, group.FileName)
 
+	content, err := w.render(group)
+	if err != nil {
+		return err
+	}
+
 	if w.dryRun {
-		fmt.Printf("Would create file: %s\n", filePath)
-		fmt.Printf("  Block type: %s\n", group.BlockType)
+		attrs := []any{"path", filePath}
+		if group.BlockType != "" {
+			attrs = append(attrs, "block_type", group.BlockType)
+		}
 		if group.SubType != "" {
-			fmt.Printf("  Sub type: %s\n", group.SubType)
+			attrs = append(attrs, "sub_type", group.SubType)
 		}
-		fmt.Printf("  Number of blocks: %d\n", len(group.Blocks))
-		fmt.Println()
+		w.log.Info("Would create file", append(attrs, "blocks", len(group.Blocks))...)
+		w.record(filePath, w.planMerge(filePath, group.FileName, content))
 		return nil
 	}
 
+	return w.writeFile(filePath, group.FileName, content)
+}
+
+// render generates the formatted content of a group's file.
+func (w *Writer) render(group *types.BlockGroup) ([]byte, error) {
 	file := hclwrite.NewEmptyFile()
 	rootBody := file.Body()
+	appendComment(rootBody, w.license)
+	appendComment(rootBody, w.header)
+
+	blocks, sectionCount := group.Blocks, 0
+	if w.options.SectionSeparators {
+		blocks, sectionCount = sections(group.Blocks)
+	}
 
-	for i, block := range group.Blocks {
-		if block.LeadingComments != "" {
+	previousSection := ""
+	for i, block := range blocks {
+		if i > 0 {
+			rootBody.AppendNewline()
+		}
+		comments := w.stripGenerated(block.LeadingComments)
+		if section := sectionName(block); sectionCount > 1 && section != previousSection {
 			if i > 0 {
 				rootBody.AppendNewline()
 			}
-
-			for line := range strings.SplitSeq(block.LeadingComments, "\n") {
-				if line != "" {
-					rootBody.AppendUnstructuredTokens(hclwrite.Tokens{
-						{Type: hclsyntax.TokenComment, Bytes: []byte(line)},
-						{Type: hclsyntax.TokenNewline, Bytes: []byte("\n")},
-					})
-				} else {
-					rootBody.AppendNewline()
-				}
-			}
-			rootBody.AppendNewline()
-		} else if i > 0 {
-			rootBody.AppendNewline()
+			appendComment(rootBody, []string{"# --- " + section + " ---"})
+			previousSection = section
+			comments = strings.TrimLeft(comments, "\n")
+		}
+		if comments != "" {
+			appendComment(rootBody, strings.Split(comments, "\n"))
 		}
 
 		if block.RawBody != "" {
@@ -123,27 +227,93 @@ func (w *Writer) writeGroup(group *types.BlockGroup) error {
 		} else {
 			newBlock := rootBody.AppendNewBlock(block.Type, block.Labels)
 			if err := w.copyBlockBody(block.Body, newBlock.Body()); err != nil {
-				return fmt.Errorf("failed to copy block body: %w", err)
+				return nil, fmt.Errorf("failed to copy block body: %w", err)
 			}
 		}
 	}
 
-	content := file.Bytes()
-	formattedContent := hclwrite.Format(content)
+	return hclwrite.Format(file.Bytes()), nil
+}
 
+// writeFile writes generated content to filePath in the configured format,
+// merging it with an existing file. Files whose content and layout already
+// match are left untouched.
+func (w *Writer) writeFile(filePath, fileName string, formattedContent []byte) error {
 	// Check if file already exists with same content (for idempotency)
-	if existingContent, err := os.ReadFile(filepath.Clean(filePath)); err == nil {
-		if normalizeContent(existingContent) == normalizeContent(formattedContent) {
+	existingContent, err := w.fsys.ReadFile(filePath)
+	existed := err == nil
+	if existed {
+		existingFormat := fileformat.Detect(existingContent, 0)
+		existingContent = fileformat.Decode(existingContent)
+		formattedContent, err = w.mergeExisting(filePath, fileName, existingContent, formattedContent)
+		if err != nil {
+			return err
+		}
+		if normalizeContent(existingContent) == normalizeContent(formattedContent) &&
+			existingFormat.CRLF == w.options.Format.CRLF && existingFormat.BOM == w.options.Format.BOM {
 			// File already exists with same content, skip writing
-			return nil
+			w.log.Debug("File is up to date", "path", filePath)
+			return w.ensureMode(filePath)
 		}
 	}
 
-	if err := os.WriteFile(filePath, formattedContent, 0600); err != nil {
+	mode := w.options.Format.Mode
+	if mode == 0 {
+		mode = fileformat.DefaultMode
+	}
+	w.record(filePath, formattedContent)
+	if err := w.fsys.WriteFile(filePath, fileformat.Encode(formattedContent, w.options.Format), mode); err != nil {
 		return fmt.Errorf("failed to write file %s: %w", filePath, err)
 	}
+	// WriteFile keeps the mode of an existing file
+	if err := w.ensureMode(filePath); err != nil {
+		return err
+	}
+
+	if existed {
+		w.log.Info("Updated file", "path", filePath)
+	} else {
+		w.log.Info("Created file", "path", filePath)
+	}
+	return nil
+}
+
+// Operations returns the files written, or planned in dry-run mode, by
+// WriteGroups. Files left unchanged are not included.
+func (w *Writer) Operations() []types.FileOperation {
+	return w.operations
+}
+
+// record notes that filePath is about to be written.
+func (w *Writer) record(filePath string, content []byte) {
+	action := types.ActionCreate
+	if _, err := w.fsys.Stat(filePath); err == nil {
+		action = types.ActionUpdate
+	}
+	w.operations = append(w.operations, types.FileOperation{
+		Action:  action,
+		Path:    filePath,
+		Content: fileformat.Encode(content, w.options.Format),
+	})
+}
 
-	fmt.Printf("Created file: %s\n", filePath)
+// ensureMode applies the configured permissions to filePath. Without a
+// configured mode, existing files keep their permissions.
+func (w *Writer) ensureMode(filePath string) error {
+	mode := w.options.Format.Mode
+	if mode == 0 {
+		return nil
+	}
+	stat, err := w.fsys.Stat(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to stat file %s: %w", filePath, err)
+	}
+	if stat.Mode().Perm() == mode {
+		return nil
+	}
+	if err := w.fsys.Chmod(filePath, mode); err != nil {
+		return fmt.Errorf("failed to set permissions of %s: %w", filePath, err)
+	}
 	return nil
 }
 
@@ -263,7 +433,7 @@ func (w *Writer) setObjectAttributeSimple(targetBody *hclwrite.Body, name string
 func (w *Writer) copyBlockBodyGeneric(sourceBody hcl.Body, targetBody *hclwrite.Body) error {
 	_, remaining, diags := sourceBody.PartialContent(emptyBlockSchema)
 	if diags.HasErrors() {
-		fmt.Printf("Warning: HCL parsing diagnostics: %v\n", diags)
+		w.log.Warn("HCL parsing diagnostics", "error", diags)
 	}
 
 	w.copyAttributes(sourceBody, targetBody)
@@ -364,6 +534,11 @@ func (w *Writer) buildTemplateTokens(valueExpr *hclsyntax.TemplateExpr) hclwrite
 }
 
 func (w *Writer) appendRawBlock(targetBody *hclwrite.Body, block *types.Block) {
+	rawBody := block.RawBody
+	if w.options.NormalizeBlocks {
+		rawBody = normalizeBody(block.Type, rawBody)
+	}
+
 	var blockTokens hclwrite.Tokens
 
 	blockTokens = append(blockTokens, &hclwrite.Token{
@@ -394,7 +569,7 @@ func (w *Writer) appendRawBlock(targetBody *hclwrite.Body, block *types.Block) {
 		},
 		&hclwrite.Token{
 			Type:  hclsyntax.TokenNewline,
-			Bytes: []byte("\n" + strings.TrimSuffix(strings.TrimPrefix(block.RawBody, "\n"), "\n") + "\n"),
+			Bytes: []byte("\n" + strings.TrimSuffix(strings.TrimPrefix(rawBody, "\n"), "\n") + "\n"),
 		},
 		&hclwrite.Token{
 			Type:  hclsyntax.TokenCBrace,
@@ -1,6 +1,7 @@
 package writer_test
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
@@ -68,7 +69,7 @@ resource "aws_instance" "web" {
 		createTestBlockGroup("resource__aws_instance.tf", "resource", []*types.Block{block}),
 	}
 
-	err := w.WriteGroups(groups)
+	err := w.WriteGroups(context.Background(), groups)
 	if err != nil {
 		t.Fatalf("WriteGroups failed: %v", err)
 	}
@@ -107,7 +108,7 @@ resource "aws_instance" "web" {
 		createTestBlockGroup("resource__aws_instance.tf", "resource", []*types.Block{resourceBlock}),
 	}
 
-	err := w.WriteGroups(groups)
+	err := w.WriteGroups(context.Background(), groups)
 	if err != nil {
 		t.Fatalf("WriteGroups failed: %v", err)
 	}
@@ -167,7 +168,7 @@ variable "key_name" {
 		createTestBlockGroup("variables.tf", "variable", []*types.Block{variable1, variable2}),
 	}
 
-	err := w.WriteGroups(groups)
+	err := w.WriteGroups(context.Background(), groups)
 	if err != nil {
 		t.Fatalf("WriteGroups failed: %v", err)
 	}
@@ -207,7 +208,7 @@ output "instance_id" {
 		createTestBlockGroup("outputs.tf", "output", []*types.Block{block}),
 	}
 
-	err := w.WriteGroups(groups)
+	err := w.WriteGroups(context.Background(), groups)
 	if err != nil {
 		t.Fatalf("WriteGroups failed: %v", err)
 	}
@@ -239,8 +240,98 @@ func TestNewWriter(t *testing.T) {
 		createTestBlockGroup("test.tf", "variable", []*types.Block{block}),
 	}
 
-	err := testWriter.WriteGroups(groups)
+	err := testWriter.WriteGroups(context.Background(), groups)
 	if err != nil {
 		t.Errorf("Writer should work correctly: %v", err)
 	}
 }
+
+func TestWriteGroupsFormat(t *testing.T) {
+	tmpDir := t.TempDir()
+	block := parseHCLBlock(t, `
+variable "region" {
+  type = string
+}
+`)
+	groups := []*types.BlockGroup{createTestBlockGroup("variables.tf", "variable", []*types.Block{block})}
+	format := types.FileFormat{CRLF: true, BOM: true, Mode: 0640}
+
+	if err := writer.NewWithOptions(tmpDir, false, writer.Options{Format: format}).WriteGroups(context.Background(), groups); err != nil {
+		t.Fatalf("WriteGroups failed: %v", err)
+	}
+
+	target := filepath.Join(tmpDir, "variables.tf")
+	content, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	want := "\xEF\xBB\xBFvariable \"region\" {\r\n  type = string\r\n}\r\n"
+	if string(content) != want {
+		t.Errorf("Expected %q, got %q", want, content)
+	}
+	stat, err := os.Stat(target)
+	if err != nil {
+		t.Fatalf("Failed to stat output file: %v", err)
+	}
+	if stat.Mode().Perm() != 0640 {
+		t.Errorf("Expected mode 0640, got %o", stat.Mode().Perm())
+	}
+
+	// Switching back to LF rewrites the file even though its content is unchanged
+	if err := writer.NewWithOptions(tmpDir, false, writer.Options{Format: types.FileFormat{Mode: 0600}}).WriteGroups(context.Background(), groups); err != nil {
+		t.Fatalf("WriteGroups failed: %v", err)
+	}
+	content, err = os.ReadFile(target)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	if string(content) != "variable \"region\" {\n  type = string\n}\n" {
+		t.Errorf("Expected LF content without byte order mark, got %q", content)
+	}
+	if stat, _ := os.Stat(target); stat.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600, got %o", stat.Mode().Perm())
+	}
+}
+
+func TestWriteGroupsHeader(t *testing.T) {
+	tmpDir := t.TempDir()
+	target := filepath.Join(tmpDir, "network.tf")
+	existing := `# Generated file
+
+# --- aws_vpn_gateway ---
+
+resource "aws_vpn_gateway" "legacy" {
+  vpc_id = "vpc-123"
+}
+`
+	if err := os.WriteFile(target, []byte(existing), 0600); err != nil {
+		t.Fatalf("Failed to create target file: %v", err)
+	}
+
+	block := parseHCLBlock(t, `
+resource "aws_vpc" "main" {
+  cidr_block = "10.0.0.0/16"
+}
+`)
+	groups := []*types.BlockGroup{createTestBlockGroup("network.tf", "resource", []*types.Block{block})}
+	options := writer.Options{Header: "Generated file", SectionSeparators: true}
+
+	if err := writer.NewWithOptions(tmpDir, false, options).WriteGroups(context.Background(), groups); err != nil {
+		t.Fatalf("WriteGroups failed: %v", err)
+	}
+
+	content, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatalf("Failed to read target file: %v", err)
+	}
+	got := string(content)
+	if !strings.HasPrefix(got, "# Generated file\n\nresource \"aws_vpc\" \"main\"") {
+		t.Errorf("Expected header at the top, got:\n%s", got)
+	}
+	if strings.Count(got, "# Generated file") != 1 || strings.Contains(got, "# --- aws_vpn_gateway ---") {
+		t.Errorf("Expected header and separators of preserved blocks not to be duplicated, got:\n%s", got)
+	}
+	if !strings.Contains(got, `resource "aws_vpn_gateway" "legacy"`) {
+		t.Errorf("Expected unmanaged block to be preserved, got:\n%s", got)
+	}
+}
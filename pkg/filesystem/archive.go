@@ -0,0 +1,194 @@
+package filesystem
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maxArchiveFileSize limits the size of a single archive member to prevent
+// decompression bombs.
+const maxArchiveFileSize = 64 * 1024 * 1024
+
+// ReadTar loads the regular files and directories of a tar archive into a
+// Memory file system, using the member names as relative paths.
+func ReadTar(r io.Reader) (*Memory, error) {
+	m := NewMemory()
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return m, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tar archive: %w", err)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			name, err := memberPath(header.Name)
+			if err != nil {
+				return nil, err
+			}
+			if err := m.MkdirAll(name, 0750); err != nil {
+				return nil, err
+			}
+		case tar.TypeReg:
+			if err := m.addMember(header.Name, tr, header.FileInfo().Mode()); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// ReadZip loads the files and directories of a zip archive into a Memory file
+// system, using the member names as relative paths.
+func ReadZip(r io.ReaderAt, size int64) (*Memory, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read zip archive: %w", err)
+	}
+
+	m := NewMemory()
+	for _, member := range zr.File {
+		if member.FileInfo().IsDir() {
+			name, err := memberPath(member.Name)
+			if err != nil {
+				return nil, err
+			}
+			if err := m.MkdirAll(name, 0750); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if !member.Mode().IsRegular() {
+			continue
+		}
+		rc, err := member.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", member.Name, err)
+		}
+		err = m.addMember(member.Name, rc, member.Mode())
+		if closeErr := rc.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// WriteTar writes the files of m to a tar archive in lexical order.
+func (m *Memory) WriteTar(w io.Writer) error {
+	tw := tar.NewWriter(w)
+	for _, name := range m.Files() {
+		data, info, err := m.member(name)
+		if err != nil {
+			return err
+		}
+		header := &tar.Header{
+			Name:    filepath.ToSlash(name),
+			Mode:    int64(info.Mode().Perm()),
+			Size:    int64(len(data)),
+			ModTime: info.ModTime().Truncate(time.Second),
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to write tar header for %s: %w", name, err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+	return tw.Close()
+}
+
+// WriteZip writes the files of m to a zip archive in lexical order.
+func (m *Memory) WriteZip(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	for _, name := range m.Files() {
+		data, info, err := m.member(name)
+		if err != nil {
+			return err
+		}
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return fmt.Errorf("failed to create zip header for %s: %w", name, err)
+		}
+		header.Name = filepath.ToSlash(name)
+		header.Method = zip.Deflate
+		fw, err := zw.CreateHeader(header)
+		if err != nil {
+			return fmt.Errorf("failed to write zip header for %s: %w", name, err)
+		}
+		if _, err := fw.Write(data); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+	return zw.Close()
+}
+
+// WriteTxtar writes the files of m in lexical order as a txtar archive: each
+// file is introduced by a "-- name --" line and ends with a newline.
+func (m *Memory) WriteTxtar(w io.Writer) error {
+	for _, name := range m.Files() {
+		data, err := m.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			data = append(data, '\n')
+		}
+		if _, err := fmt.Fprintf(w, "-- %s --\n%s", filepath.ToSlash(name), data); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) addMember(name string, r io.Reader, mode fs.FileMode) error {
+	name, err := memberPath(name)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(io.LimitReader(r, maxArchiveFileSize+1))
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if len(data) > maxArchiveFileSize {
+		return fmt.Errorf("archive member too large (max %d bytes): %s", maxArchiveFileSize, name)
+	}
+	if err := m.MkdirAll(filepath.Dir(name), 0750); err != nil {
+		return err
+	}
+	return m.WriteFile(name, data, mode.Perm())
+}
+
+func (m *Memory) member(name string) ([]byte, fs.FileInfo, error) {
+	data, err := m.ReadFile(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := m.Stat(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, info, nil
+}
+
+// memberPath converts an archive member name to a relative host path,
+// rejecting names that escape the archive root.
+func memberPath(name string) (string, error) {
+	clean := path.Clean(strings.TrimPrefix(name, "./"))
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("archive member outside the archive root: %s", name)
+	}
+	return filepath.FromSlash(clean), nil
+}
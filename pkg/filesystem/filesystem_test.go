@@ -0,0 +1,176 @@
+package filesystem_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/filesystem"
+)
+
+func newTree(t *testing.T) *filesystem.Memory {
+	t.Helper()
+	m := filesystem.NewMemory()
+	for _, dir := range []string{"a/b", "a/c"} {
+		if err := m.MkdirAll(dir, 0750); err != nil {
+			t.Fatalf("MkdirAll failed: %v", err)
+		}
+	}
+	for _, name := range []string{"a/main.tf", "a/b/vpc.tf", "a/c/README.md"} {
+		if err := m.WriteFile(name, []byte(name), 0640); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+	}
+	return m
+}
+
+func TestMemory(t *testing.T) {
+	m := newTree(t)
+
+	if err := m.WriteFile("missing/main.tf", nil, 0600); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected writing into a missing directory to fail, got %v", err)
+	}
+
+	if err := m.Rename("a/main.tf", "a/b/main.tf"); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	data, err := m.ReadFile("a/b/main.tf")
+	if err != nil || string(data) != "a/main.tf" {
+		t.Errorf("Expected renamed content, got %q (%v)", data, err)
+	}
+	info, err := m.Stat("a/b/main.tf")
+	if err != nil || info.Mode().Perm() != 0640 || info.IsDir() {
+		t.Errorf("Unexpected file info %v (%v)", info, err)
+	}
+
+	if err := m.Remove("a/b"); err == nil {
+		t.Error("Expected removing a non-empty directory to fail")
+	}
+	if err := m.Remove("a/b/main.tf"); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if _, err := m.Stat("a/b/main.tf"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected removed file to be gone, got %v", err)
+	}
+
+	entries, err := m.ReadDir("a")
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if !slices.Equal(names, []string{"b", "c"}) {
+		t.Errorf("Expected entries [b c], got %v", names)
+	}
+}
+
+func TestMemoryWalk(t *testing.T) {
+	m := newTree(t)
+
+	var visited []string
+	err := m.Walk("a", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && info.Name() == "c" {
+			return filepath.SkipDir
+		}
+		visited = append(visited, filepath.ToSlash(path))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	expected := []string{"a", "a/b", "a/b/vpc.tf", "a/main.tf"}
+	if !slices.Equal(visited, expected) {
+		t.Errorf("Expected %v, got %v", expected, visited)
+	}
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	m := newTree(t)
+
+	var tarball bytes.Buffer
+	if err := m.WriteTar(&tarball); err != nil {
+		t.Fatalf("WriteTar failed: %v", err)
+	}
+	fromTar, err := filesystem.ReadTar(&tarball)
+	if err != nil {
+		t.Fatalf("ReadTar failed: %v", err)
+	}
+
+	var zipball bytes.Buffer
+	if err := fromTar.WriteZip(&zipball); err != nil {
+		t.Fatalf("WriteZip failed: %v", err)
+	}
+	fromZip, err := filesystem.ReadZip(bytes.NewReader(zipball.Bytes()), int64(zipball.Len()))
+	if err != nil {
+		t.Fatalf("ReadZip failed: %v", err)
+	}
+
+	if !slices.Equal(fromZip.Files(), m.Files()) {
+		t.Errorf("Expected files %v, got %v", m.Files(), fromZip.Files())
+	}
+	for _, name := range m.Files() {
+		data, err := fromZip.ReadFile(name)
+		if err != nil || string(data) != filepath.ToSlash(name) {
+			t.Errorf("Unexpected content of %s: %q (%v)", name, data, err)
+		}
+		if info, _ := fromZip.Stat(name); info.Mode().Perm() != 0640 {
+			t.Errorf("Expected %s to keep mode 0640, got %o", name, info.Mode().Perm())
+		}
+	}
+}
+
+func TestArchiveRejectsEscapingMembers(t *testing.T) {
+	var tarball bytes.Buffer
+	tw := tar.NewWriter(&tarball)
+	if err := tw.WriteHeader(&tar.Header{Name: "../evil.tf", Mode: 0600, Typeflag: tar.TypeReg}); err != nil {
+		t.Fatalf("Failed to write tar header: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Failed to close tar writer: %v", err)
+	}
+	if _, err := filesystem.ReadTar(&tarball); err == nil {
+		t.Error("Expected tar member outside the archive root to be rejected")
+	}
+
+	var zipball bytes.Buffer
+	zw := zip.NewWriter(&zipball)
+	if _, err := zw.Create("/etc/evil.tf"); err != nil {
+		t.Fatalf("Failed to create zip member: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to close zip writer: %v", err)
+	}
+	if _, err := filesystem.ReadZip(bytes.NewReader(zipball.Bytes()), int64(zipball.Len())); err == nil {
+		t.Error("Expected zip member outside the archive root to be rejected")
+	}
+}
+
+func TestWriteTxtar(t *testing.T) {
+	m := filesystem.NewMemory()
+	if err := m.WriteFile("variables.tf", []byte("variable \"region\" {}"), 0600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if err := m.WriteFile("outputs.tf", []byte("output \"id\" {}\n"), 0600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := m.WriteTxtar(&buf); err != nil {
+		t.Fatalf("WriteTxtar failed: %v", err)
+	}
+	expected := "-- outputs.tf --\noutput \"id\" {}\n-- variables.tf --\nvariable \"region\" {}\n"
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
@@ -0,0 +1,263 @@
+package filesystem
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Memory is an in-memory FileSystem. Directories exist when created with
+// MkdirAll or when they contain files. It is safe for concurrent use.
+type Memory struct {
+	mu    sync.RWMutex
+	files map[string]*memFile
+	dirs  map[string]bool
+}
+
+type memFile struct {
+	data    []byte
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+// NewMemory returns an empty in-memory FileSystem.
+func NewMemory() *Memory {
+	return &Memory{
+		files: make(map[string]*memFile),
+		dirs:  make(map[string]bool),
+	}
+}
+
+// Files returns the paths of all files in lexical order.
+func (m *Memory) Files() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	names := make([]string, 0, len(m.files))
+	for name := range m.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (m *Memory) ReadFile(name string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	f, ok := m.files[filepath.Clean(name)]
+	if !ok {
+		return nil, pathError("open", name, fs.ErrNotExist)
+	}
+	return append([]byte{}, f.data...), nil
+}
+
+func (m *Memory) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = filepath.Clean(name)
+	if m.isDir(name) {
+		return pathError("open", name, fs.ErrInvalid)
+	}
+	if !m.isDir(filepath.Dir(name)) {
+		return pathError("open", name, fs.ErrNotExist)
+	}
+	// Like os.WriteFile, an existing file keeps its permissions
+	if f, ok := m.files[name]; ok {
+		perm = f.mode
+	}
+	m.files[name] = &memFile{data: append([]byte{}, data...), mode: perm.Perm(), modTime: time.Now()}
+	return nil
+}
+
+func (m *Memory) Stat(name string) (fs.FileInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	name = filepath.Clean(name)
+	if f, ok := m.files[name]; ok {
+		return &memInfo{name: filepath.Base(name), size: int64(len(f.data)), mode: f.mode, modTime: f.modTime}, nil
+	}
+	if m.isDir(name) {
+		return &memInfo{name: filepath.Base(name), mode: fs.ModeDir | 0750}, nil
+	}
+	return nil, pathError("stat", name, fs.ErrNotExist)
+}
+
+func (m *Memory) Chmod(name string, mode fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, ok := m.files[filepath.Clean(name)]
+	if !ok {
+		return pathError("chmod", name, fs.ErrNotExist)
+	}
+	f.mode = mode.Perm()
+	return nil
+}
+
+func (m *Memory) MkdirAll(path string, _ fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for dir := filepath.Clean(path); ; dir = filepath.Dir(dir) {
+		if _, ok := m.files[dir]; ok {
+			return pathError("mkdir", dir, fs.ErrExist)
+		}
+		m.dirs[dir] = true
+		if parent := filepath.Dir(dir); parent == dir {
+			return nil
+		}
+	}
+}
+
+func (m *Memory) ReadDir(name string) ([]fs.DirEntry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	name = filepath.Clean(name)
+	if !m.isDir(name) {
+		return nil, pathError("open", name, fs.ErrNotExist)
+	}
+
+	children := make(map[string]*memInfo)
+	for path, f := range m.files {
+		if child, rest, ok := m.child(name, path); ok {
+			if rest {
+				children[child] = &memInfo{name: child, mode: fs.ModeDir | 0750}
+			} else {
+				children[child] = &memInfo{name: child, size: int64(len(f.data)), mode: f.mode, modTime: f.modTime}
+			}
+		}
+	}
+	for dir := range m.dirs {
+		if child, _, ok := m.child(name, dir); ok {
+			children[child] = &memInfo{name: child, mode: fs.ModeDir | 0750}
+		}
+	}
+
+	entries := make([]fs.DirEntry, 0, len(children))
+	for _, info := range children {
+		entries = append(entries, fs.FileInfoToDirEntry(info))
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+func (m *Memory) Rename(oldpath, newpath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	oldpath, newpath = filepath.Clean(oldpath), filepath.Clean(newpath)
+	f, ok := m.files[oldpath]
+	if !ok {
+		return pathError("rename", oldpath, fs.ErrNotExist)
+	}
+	if !m.isDir(filepath.Dir(newpath)) {
+		return pathError("rename", newpath, fs.ErrNotExist)
+	}
+	delete(m.files, oldpath)
+	m.files[newpath] = f
+	return nil
+}
+
+func (m *Memory) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = filepath.Clean(name)
+	if _, ok := m.files[name]; ok {
+		delete(m.files, name)
+		return nil
+	}
+	if m.dirs[name] {
+		for path := range m.files {
+			if _, _, ok := m.child(name, path); ok {
+				return pathError("remove", name, fs.ErrExist)
+			}
+		}
+		delete(m.dirs, name)
+		return nil
+	}
+	return pathError("remove", name, fs.ErrNotExist)
+}
+
+func (m *Memory) Walk(root string, fn filepath.WalkFunc) error {
+	root = filepath.Clean(root)
+	info, err := m.Stat(root)
+	if err != nil {
+		return fn(root, nil, err)
+	}
+	err = m.walk(root, info, fn)
+	if errors.Is(err, filepath.SkipDir) || errors.Is(err, filepath.SkipAll) {
+		return nil
+	}
+	return err
+}
+
+func (m *Memory) walk(path string, info fs.FileInfo, fn filepath.WalkFunc) error {
+	if !info.IsDir() {
+		return fn(path, info, nil)
+	}
+
+	entries, err := m.ReadDir(path)
+	if err := fn(path, info, err); err != nil || entries == nil {
+		return err
+	}
+	for _, entry := range entries {
+		child := filepath.Join(path, entry.Name())
+		childInfo, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if err := m.walk(child, childInfo, fn); err != nil {
+			if !errors.Is(err, filepath.SkipDir) {
+				return err
+			}
+			// SkipDir on a file skips the rest of its directory
+			if !childInfo.IsDir() {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// isDir reports whether dir was created or contains files. The current
+// directory and the root always exist. Callers hold the lock.
+func (m *Memory) isDir(dir string) bool {
+	if dir == "." || dir == string(filepath.Separator) || m.dirs[dir] {
+		return true
+	}
+	for path := range m.files {
+		if _, _, ok := m.child(dir, path); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// child returns the name of the entry of dir that path is or is inside, and
+// whether path is deeper than that entry.
+func (m *Memory) child(dir, path string) (name string, deeper, ok bool) {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false, false
+	}
+	name, rest, found := strings.Cut(rel, string(filepath.Separator))
+	return name, found && rest != "", true
+}
+
+func pathError(op, path string, err error) error {
+	return &fs.PathError{Op: op, Path: path, Err: err}
+}
+
+type memInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (i *memInfo) Name() string       { return i.name }
+func (i *memInfo) Size() int64        { return i.size }
+func (i *memInfo) Mode() fs.FileMode  { return i.mode }
+func (i *memInfo) ModTime() time.Time { return i.modTime }
+func (i *memInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i *memInfo) Sys() any           { return nil }
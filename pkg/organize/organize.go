@@ -0,0 +1,137 @@
+// Package organize splits Terraform configurations into files by block type
+// and configured groups. It is the library form of the tf-file-organize CLI
+// and logs nothing unless Options.Logger is set.
+package organize
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/tomoya-namekawa/tf-file-organize/internal/dirlock"
+	"github.com/tomoya-namekawa/tf-file-organize/internal/usecase"
+	"github.com/tomoya-namekawa/tf-file-organize/internal/validation"
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/filesystem"
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/types"
+)
+
+// Options configures an Organize call. They mirror the flags of the run and
+// plan commands.
+type Options struct {
+	InputPath       string // Terraform file or directory to organize (required)
+	OutputDir       string // Output directory (default: the input directory)
+	ConfigFile      string // Configuration file (default: discovered from InputPath)
+	DryRun          bool   // Plan without changing any file
+	Recursive       bool   // Also organize the .tf files of subdirectories, combined into the output directory
+	PerDirectory    bool   // With Recursive, organize every directory containing .tf files in place instead
+	Backup          bool   // Move source files to a backup directory instead of removing them
+	Force           bool   // Overwrite output files whose content cannot be merged
+	NormalizeBlocks bool   // Reorder attributes inside blocks into canonical order
+	Git             bool   // Move source files with git mv to their main output file and stage the changes
+	AllowDirty      bool   // Allow Git on a worktree with uncommitted changes
+	Since           string // Only organize modules with .tf files changed since this git ref
+	Jobs            int    // Files parsed and modules planned at the same time (default: the number of CPUs)
+
+	// Logger receives the progress and warning events the CLI prints. Nil
+	// discards them.
+	Logger *slog.Logger
+	// FS holds the Terraform files to organize and receives the results. Nil
+	// means the operating system. With any other file system, ConfigFile is
+	// still read from the operating system and configuration files are not
+	// discovered.
+	FS filesystem.FileSystem
+}
+
+// Result summarizes an Organize call.
+type Result struct {
+	ProcessedFiles int    // Source files parsed
+	TotalBlocks    int    // Blocks found in the source files
+	FileGroups     int    // Output files produced
+	OutputDir      string // Directory the files were written to
+	DryRun         bool   // Whether the call only planned the changes
+
+	// Groups lists the blocks placed in each output file. In per-directory
+	// mode file names are relative to each module directory.
+	Groups []*types.BlockGroup
+	// Operations lists the files written, removed or backed up, in order.
+	// In dry-run mode they are the planned operations.
+	Operations []types.FileOperation
+	// StaleFiles lists files generated by a previous run that are no longer
+	// produced, and were removed or would be removed.
+	StaleFiles []string
+}
+
+// InterruptedError is returned by Organize when ctx is canceled while files
+// are being changed. It lists the modules that were organized and those left
+// unchanged: each module is changed atomically.
+type InterruptedError = usecase.InterruptedError
+
+// LockedError is returned by Organize when another run holds the lock of an
+// output directory. It names the run holding the lock.
+type LockedError = dirlock.LockedError
+
+// Organize organizes the Terraform files at opts.InputPath. Canceling ctx
+// stops the run after the module being written is finished or rolled back.
+func Organize(ctx context.Context, opts Options) (*Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := validate(opts); err != nil {
+		return nil, err
+	}
+
+	uc := usecase.NewOrganizeFilesUsecase()
+	uc.SetLogger(opts.Logger)
+	uc.SetJobs(opts.Jobs)
+	if opts.FS != nil {
+		uc.SetFileSystem(opts.FS)
+	}
+
+	resp, err := uc.Execute(ctx, &usecase.OrganizeFilesRequest{
+		InputPath:       opts.InputPath,
+		OutputDir:       opts.OutputDir,
+		ConfigFile:      opts.ConfigFile,
+		DryRun:          opts.DryRun,
+		Recursive:       opts.Recursive,
+		PerDirectory:    opts.PerDirectory,
+		Backup:          opts.Backup,
+		Force:           opts.Force,
+		NormalizeBlocks: opts.NormalizeBlocks,
+		Git:             opts.Git,
+		AllowDirty:      opts.AllowDirty,
+		Since:           opts.Since,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		ProcessedFiles: resp.ProcessedFiles,
+		TotalBlocks:    resp.TotalBlocks,
+		FileGroups:     resp.FileGroups,
+		OutputDir:      resp.OutputDir,
+		DryRun:         resp.WasDryRun,
+		Groups:         resp.Groups,
+		Operations:     resp.Operations,
+		StaleFiles:     resp.StaleFiles,
+	}, nil
+}
+
+// validate checks the options that do not depend on the file system. Unlike
+// the CLI, paths are not restricted to the working directory.
+func validate(opts Options) error {
+	if opts.InputPath == "" {
+		return fmt.Errorf("input path is required")
+	}
+	if opts.Jobs < 0 {
+		return fmt.Errorf("jobs must not be negative: %d", opts.Jobs)
+	}
+	if opts.PerDirectory && !opts.Recursive {
+		return fmt.Errorf("per-directory mode requires recursive mode")
+	}
+	if opts.Git && opts.Backup {
+		return fmt.Errorf("git mode cannot be combined with backup: git history keeps the source files")
+	}
+	// Validate flag combinations
+	return validation.ValidateFlagCombination(opts.OutputDir, opts.Recursive)
+}
@@ -0,0 +1,571 @@
+package organize_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/tomoya-namekawa/tf-file-organize/internal/dirlock"
+	"github.com/tomoya-namekawa/tf-file-organize/internal/logging"
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/filesystem"
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/organize"
+	"github.com/tomoya-namekawa/tf-file-organize/pkg/types"
+)
+
+const source = `resource "aws_vpc" "main" {
+  cidr_block = "10.0.0.0/16"
+}
+
+variable "region" {
+  type = string
+}
+`
+
+func writeSource(t *testing.T) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.tf")
+	if err := os.WriteFile(path, []byte(source), 0600); err != nil {
+		t.Fatalf("Failed to create source file: %v", err)
+	}
+	return dir, path
+}
+
+// captureStdout returns everything written to os.Stdout while fn runs.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	fn()
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close pipe: %v", err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Failed to read pipe: %v", err)
+	}
+	return string(data)
+}
+
+func TestOrganizeDryRun(t *testing.T) {
+	dir, path := writeSource(t)
+
+	var result *organize.Result
+	var err error
+	stdout := captureStdout(t, func() {
+		result, err = organize.Organize(context.Background(), organize.Options{InputPath: dir, DryRun: true})
+	})
+	if err != nil {
+		t.Fatalf("Organize failed: %v", err)
+	}
+	if stdout != "" {
+		t.Errorf("Expected no output on stdout, got:\n%s", stdout)
+	}
+
+	if !result.DryRun || result.ProcessedFiles != 1 || result.TotalBlocks != 2 || len(result.Groups) != 2 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	expected := map[string]types.FileAction{
+		filepath.Join(dir, "resource__aws_vpc.tf"): types.ActionCreate,
+		filepath.Join(dir, "variables.tf"):         types.ActionCreate,
+		path:                                       types.ActionRemove,
+	}
+	if len(result.Operations) != len(expected) {
+		t.Errorf("Expected %d operations, got %+v", len(expected), result.Operations)
+	}
+	for _, op := range result.Operations {
+		if expected[op.Path] != op.Action {
+			t.Errorf("Unexpected operation %+v", op)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "variables.tf")); !os.IsNotExist(err) {
+		t.Error("Expected dry run not to write files")
+	}
+}
+
+func TestOrganize(t *testing.T) {
+	dir, path := writeSource(t)
+
+	result, err := organize.Organize(context.Background(), organize.Options{InputPath: dir, Backup: true})
+	if err != nil {
+		t.Fatalf("Organize failed: %v", err)
+	}
+
+	for _, op := range result.Operations {
+		if op.Action == types.ActionBackup {
+			if op.Path != path || op.Target != filepath.Join(dir, "backup", "main.tf") {
+				t.Errorf("Unexpected backup operation %+v", op)
+			}
+			continue
+		}
+		if _, err := os.Stat(op.Path); err != nil {
+			t.Errorf("Expected %s to be written: %v", op.Path, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "backup", "main.tf")); err != nil {
+		t.Errorf("Expected source file to be backed up: %v", err)
+	}
+}
+
+func TestOrganizeLocked(t *testing.T) {
+	dir, path := writeSource(t)
+	lock, _, err := dirlock.Acquire(dir)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+
+	_, err = organize.Organize(context.Background(), organize.Options{InputPath: dir})
+	var locked *organize.LockedError
+	if !errors.As(err, &locked) || locked.Holder == nil || locked.Holder.PID != os.Getpid() {
+		t.Fatalf("Expected LockedError naming this process, got %v", err)
+	}
+	if content, err := os.ReadFile(path); err != nil || string(content) != source {
+		t.Errorf("Expected source file to be unchanged, got %q (%v)", content, err)
+	}
+
+	// A dry run only reads the files, so it ignores the lock
+	captureStdout(t, func() {
+		_, err = organize.Organize(context.Background(), organize.Options{InputPath: dir, DryRun: true})
+	})
+	if err != nil {
+		t.Errorf("Expected a dry run to ignore the lock, got %v", err)
+	}
+
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Release failed: %v", err)
+	}
+	if _, err := organize.Organize(context.Background(), organize.Options{InputPath: dir}); err != nil {
+		t.Fatalf("Organize failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, dirlock.FileName)); !os.IsNotExist(err) {
+		t.Errorf("Expected the lock to be released after the run, got %v", err)
+	}
+}
+
+func TestOrganizeValidatesOptions(t *testing.T) {
+	if _, err := organize.Organize(context.Background(), organize.Options{}); err == nil {
+		t.Error("Expected error for missing input path")
+	}
+	if _, err := organize.Organize(context.Background(), organize.Options{InputPath: ".", Git: true, Backup: true}); err == nil {
+		t.Error("Expected error for git mode with backup")
+	}
+	if _, err := organize.Organize(context.Background(), organize.Options{InputPath: ".", Jobs: -1}); err == nil {
+		t.Error("Expected error for negative jobs")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	dir, _ := writeSource(t)
+	if _, err := organize.Organize(ctx, organize.Options{InputPath: dir}); err == nil {
+		t.Error("Expected error for canceled context")
+	}
+}
+
+func TestOrganizeArchive(t *testing.T) {
+	fsys := filesystem.NewMemory()
+	if err := fsys.MkdirAll("infra", 0750); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := fsys.WriteFile(filepath.Join("infra", "main.tf"), []byte(source), 0600); err != nil {
+		t.Fatalf("Failed to create source file: %v", err)
+	}
+	var archive bytes.Buffer
+	if err := fsys.WriteTar(&archive); err != nil {
+		t.Fatalf("WriteTar failed: %v", err)
+	}
+
+	input, err := filesystem.ReadTar(&archive)
+	if err != nil {
+		t.Fatalf("ReadTar failed: %v", err)
+	}
+	if _, err := organize.Organize(context.Background(), organize.Options{InputPath: "infra", FS: input}); err != nil {
+		t.Fatalf("Organize failed: %v", err)
+	}
+
+	archive.Reset()
+	if err := input.WriteTar(&archive); err != nil {
+		t.Fatalf("WriteTar failed: %v", err)
+	}
+	output, err := filesystem.ReadTar(&archive)
+	if err != nil {
+		t.Fatalf("ReadTar failed: %v", err)
+	}
+	expected := []string{
+		filepath.Join("infra", ".tf-file-organize.lock.json"),
+		filepath.Join("infra", "resource__aws_vpc.tf"),
+		filepath.Join("infra", "variables.tf"),
+	}
+	if got := output.Files(); !slices.Equal(got, expected) {
+		t.Errorf("Expected files %v, got %v", expected, got)
+	}
+	content, err := output.ReadFile(filepath.Join("infra", "variables.tf"))
+	if err != nil || !strings.Contains(string(content), `variable "region"`) {
+		t.Errorf("Expected variables.tf to hold the variable, got %q (%v)", content, err)
+	}
+}
+
+// initRepo creates a git repository in a temporary directory holding files,
+// committed, or skips the test if git is not installed.
+func initRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+	}
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "--allow-empty", "-m", "initial"},
+	} {
+		gitOutput(t, dir, args...)
+	}
+	return dir
+}
+
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, output)
+	}
+	return string(output)
+}
+
+func TestOrganizeGit(t *testing.T) {
+	dir := initRepo(t, map[string]string{"main.tf": `resource "aws_vpc" "main" {
+  cidr_block = "10.0.0.0/16"
+}
+
+resource "aws_vpc" "backup" {
+  cidr_block = "10.1.0.0/16"
+}
+
+variable "region" {
+  type = string
+}
+`})
+
+	// A dirty worktree is refused unless allowed
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("wip"), 0600); err != nil {
+		t.Fatalf("Failed to create untracked file: %v", err)
+	}
+	_, err := organize.Organize(context.Background(), organize.Options{InputPath: dir, Git: true})
+	if err == nil || !strings.Contains(err.Error(), "notes.txt") {
+		t.Fatalf("Expected dirty worktree error naming notes.txt, got %v", err)
+	}
+
+	var logs bytes.Buffer
+	logger, err := logging.New(&logs, logging.Options{Level: slog.LevelInfo})
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+	result, err := organize.Organize(context.Background(), organize.Options{InputPath: dir, Git: true, AllowDirty: true, Logger: logger})
+	if err != nil {
+		t.Fatalf("Organize failed: %v", err)
+	}
+	if op := result.Operations[0]; op.Action != types.ActionMove || op.Target != filepath.Join(dir, "resource__aws_vpc.tf") {
+		t.Errorf("Expected main.tf to be moved to the file receiving most blocks, got %+v", op)
+	}
+	// The moved file is rewritten in place, so it is reported as updated
+	if !strings.Contains(logs.String(), "Updated file path="+filepath.Join(dir, "resource__aws_vpc.tf")) ||
+		!strings.Contains(logs.String(), "Created file path="+filepath.Join(dir, "variables.tf")) {
+		t.Errorf("Expected the moved file to be updated and the new file created, got:\n%s", logs.String())
+	}
+
+	// Everything but the untracked file is staged, and the split is a rename
+	status := gitOutput(t, dir, "status", "--porcelain")
+	expected := `A  .tf-file-organize.lock.json
+R  main.tf -> resource__aws_vpc.tf
+A  variables.tf
+?? notes.txt
+`
+	if status != expected {
+		t.Errorf("Expected status:\n%s\ngot:\n%s", expected, status)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "main.tf")); !os.IsNotExist(err) {
+		t.Error("Expected main.tf to be moved")
+	}
+}
+
+func TestOrganizeSince(t *testing.T) {
+	dir := initRepo(t, map[string]string{})
+	for _, module := range []string{"network", "compute"} {
+		if err := os.MkdirAll(filepath.Join(dir, module), 0750); err != nil {
+			t.Fatalf("Failed to create module: %v", err)
+		}
+		// Distinct names, so the modules can also be organized together
+		content := strings.NewReplacer(`"main"`, `"`+module+`"`, `"region"`, `"`+module+`_region"`).Replace(source)
+		if err := os.WriteFile(filepath.Join(dir, module, "main.tf"), []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to create source file: %v", err)
+		}
+	}
+	gitOutput(t, dir, "add", ".")
+	gitOutput(t, dir, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "modules")
+
+	// Unchanged since HEAD: nothing is organized
+	result, err := organize.Organize(context.Background(), organize.Options{InputPath: dir, Recursive: true, PerDirectory: true, Since: "HEAD"})
+	if err != nil {
+		t.Fatalf("Organize failed: %v", err)
+	}
+	if result.ProcessedFiles != 0 || len(result.Operations) != 0 {
+		t.Errorf("Expected no module to be organized, got %+v", result)
+	}
+	result, err = organize.Organize(context.Background(), organize.Options{InputPath: dir, Recursive: true, Since: "HEAD"})
+	if err != nil {
+		t.Fatalf("Organize failed: %v", err)
+	}
+	if result.ProcessedFiles != 0 {
+		t.Errorf("Expected nothing to be organized, got %+v", result)
+	}
+
+	network, err := os.ReadFile(filepath.Join(dir, "network", "main.tf"))
+	if err != nil {
+		t.Fatalf("Failed to read source file: %v", err)
+	}
+	changed := string(network) + "\noutput \"id\" {\n  value = 1\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "network", "main.tf"), []byte(changed), 0600); err != nil {
+		t.Fatalf("Failed to change source file: %v", err)
+	}
+	result, err = organize.Organize(context.Background(), organize.Options{InputPath: dir, Recursive: true, PerDirectory: true, Since: "HEAD"})
+	if err != nil {
+		t.Fatalf("Organize failed: %v", err)
+	}
+	if result.ProcessedFiles != 1 || result.TotalBlocks != 3 {
+		t.Errorf("Expected only the changed module to be organized, got %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "network", "outputs.tf")); err != nil {
+		t.Errorf("Expected changed module to be organized: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "compute", "main.tf")); err != nil {
+		t.Errorf("Expected unchanged module to be left alone: %v", err)
+	}
+
+	// Without --per-directory the tree is organized as a whole once any module changed
+	result, err = organize.Organize(context.Background(), organize.Options{InputPath: dir, Recursive: true, DryRun: true, Since: "HEAD"})
+	if err != nil {
+		t.Fatalf("Organize failed: %v", err)
+	}
+	if result.ProcessedFiles != 4 {
+		t.Errorf("Expected the files of both modules to be organized together, got %+v", result)
+	}
+
+	if _, err := organize.Organize(context.Background(), organize.Options{InputPath: dir, Since: "no-such-ref"}); err == nil {
+		t.Error("Expected error for unknown ref")
+	}
+}
+
+// writeModules creates a tree of modules, each with several source files,
+// on the operating system file system.
+func writeModules(t testing.TB, modules, files int) string {
+	t.Helper()
+	root := t.TempDir()
+	for m := range modules {
+		dir := filepath.Join(root, fmt.Sprintf("module%03d", m))
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			t.Fatalf("Failed to create module directory: %v", err)
+		}
+		for f := range files {
+			var content strings.Builder
+			for r := range 10 {
+				fmt.Fprintf(&content, "# Subnet %d\nresource \"aws_subnet\" \"s%d_%d\" {\n  cidr_block = \"10.%d.%d.0/24\"\n  tags = {\n    Name = \"s%d\"\n  }\n}\n\n", r, f, r, f, r, r)
+			}
+			fmt.Fprintf(&content, "variable \"v%d\" {\n  type = string\n}\n", f)
+			if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("file%02d.tf", f)), []byte(content.String()), 0600); err != nil {
+				t.Fatalf("Failed to create source file: %v", err)
+			}
+		}
+	}
+	return root
+}
+
+func TestOrganizeJobs(t *testing.T) {
+	root := writeModules(t, 6, 4)
+
+	run := func(jobs int) (*organize.Result, string) {
+		var logs bytes.Buffer
+		logger, err := logging.New(&logs, logging.Options{Level: slog.LevelDebug})
+		if err != nil {
+			t.Fatalf("Failed to create logger: %v", err)
+		}
+		result, err := organize.Organize(context.Background(), organize.Options{
+			InputPath:    root,
+			Recursive:    true,
+			PerDirectory: true,
+			DryRun:       true,
+			Jobs:         jobs,
+			Logger:       logger,
+		})
+		if err != nil {
+			t.Fatalf("Organize failed with %d jobs: %v", jobs, err)
+		}
+		return result, logs.String()
+	}
+
+	serial, serialLogs := run(1)
+	if serial.ProcessedFiles != 24 || len(serial.Operations) != 36 {
+		t.Fatalf("Expected 24 files and 36 operations, got %d and %d", serial.ProcessedFiles, len(serial.Operations))
+	}
+	for range 3 {
+		parallel, parallelLogs := run(8)
+		if parallelLogs != serialLogs {
+			t.Errorf("Expected the same events in the same order, got:\n%s\nwant:\n%s", parallelLogs, serialLogs)
+		}
+		for i, op := range parallel.Operations {
+			if op.Path != serial.Operations[i].Path || !bytes.Equal(op.Content, serial.Operations[i].Content) {
+				t.Errorf("Operation %d differs: %s, want %s", i, op.Path, serial.Operations[i].Path)
+			}
+		}
+	}
+}
+
+func BenchmarkOrganize(b *testing.B) {
+	root := writeModules(b, 50, 20)
+	for _, jobs := range []int{1, 0} {
+		name := fmt.Sprintf("jobs=%d", jobs)
+		if jobs == 0 {
+			name = "jobs=cpus"
+		}
+		b.Run(name, func(b *testing.B) {
+			for b.Loop() {
+				_, err := organize.Organize(context.Background(), organize.Options{
+					InputPath:    root,
+					Recursive:    true,
+					PerDirectory: true,
+					DryRun:       true,
+					Jobs:         jobs,
+				})
+				if err != nil {
+					b.Fatalf("Organize failed: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// interruptingFS calls interrupt on the first file written in dir, after
+// writing it.
+type interruptingFS struct {
+	filesystem.FileSystem
+	dir       string
+	interrupt func() error
+}
+
+func (f *interruptingFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	if err := f.FileSystem.WriteFile(name, data, perm); err != nil {
+		return err
+	}
+	if filepath.Dir(name) == f.dir && f.interrupt != nil {
+		interrupt := f.interrupt
+		f.interrupt = nil
+		return interrupt()
+	}
+	return nil
+}
+
+// newModules creates the modules "a" and "b" in memory, with an existing
+// variables.tf in "b".
+func newModules(t *testing.T) *filesystem.Memory {
+	t.Helper()
+	fsys := filesystem.NewMemory()
+	for _, dir := range []string{"a", "b"} {
+		if err := fsys.MkdirAll(dir, 0750); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := fsys.WriteFile(filepath.Join(dir, "main.tf"), []byte(source), 0600); err != nil {
+			t.Fatalf("Failed to create source file: %v", err)
+		}
+	}
+	if err := fsys.WriteFile(filepath.Join("b", "variables.tf"), []byte("variable \"zone\" {}\n"), 0600); err != nil {
+		t.Fatalf("Failed to create output file: %v", err)
+	}
+	return fsys
+}
+
+func snapshot(t *testing.T, fsys *filesystem.Memory, dir string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	for _, name := range fsys.Files() {
+		if filepath.Dir(name) != dir {
+			continue
+		}
+		content, err := fsys.ReadFile(name)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", name, err)
+		}
+		files[name] = string(content)
+	}
+	return files
+}
+
+func TestOrganizeInterrupted(t *testing.T) {
+	fsys := newModules(t)
+	before := snapshot(t, fsys, "b")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, err := organize.Organize(ctx, organize.Options{
+		InputPath:    ".",
+		Recursive:    true,
+		PerDirectory: true,
+		FS:           &interruptingFS{FileSystem: fsys, dir: "b", interrupt: func() error { cancel(); return nil }},
+	})
+
+	var interrupted *organize.InterruptedError
+	if !errors.As(err, &interrupted) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected an interrupted error, got %v", err)
+	}
+	if !slices.Equal(interrupted.Organized, []string{"a"}) || !slices.Equal(interrupted.Unchanged, []string{"b"}) || interrupted.RolledBack != "b" {
+		t.Errorf("Unexpected state %+v", interrupted)
+	}
+	if err.Error() != "interrupted: 1 of 2 modules organized, the others are unchanged" {
+		t.Errorf("Unexpected message %q", err.Error())
+	}
+
+	if _, err := fsys.Stat(filepath.Join("a", "main.tf")); err == nil {
+		t.Error("Expected module a to be organized")
+	}
+	if after := snapshot(t, fsys, "b"); !maps.Equal(after, before) {
+		t.Errorf("Expected module b to be rolled back to %v, got %v", before, after)
+	}
+}
+
+func TestOrganizeRollsBackFailedModule(t *testing.T) {
+	fsys := newModules(t)
+	before := snapshot(t, fsys, "b")
+
+	_, err := organize.Organize(context.Background(), organize.Options{
+		InputPath: "b",
+		FS:        &interruptingFS{FileSystem: fsys, dir: "b", interrupt: func() error { return errors.New("disk full") }},
+	})
+	if err == nil || !strings.Contains(err.Error(), "disk full (changes rolled back)") {
+		t.Fatalf("Expected a rolled back write error, got %v", err)
+	}
+	if after := snapshot(t, fsys, "b"); !maps.Equal(after, before) {
+		t.Errorf("Expected module b to be rolled back to %v, got %v", before, after)
+	}
+}
@@ -1,7 +1,14 @@
 // Package types defines the core data structures used throughout the tf-file-organize application.
 package types
 
-import "github.com/hashicorp/hcl/v2"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+)
 
 // Block represents a Terraform configuration block with its metadata and source content.
 type Block struct {
@@ -15,10 +22,35 @@ type Block struct {
 	SourceFile      string    // Source file path where this block was parsed from
 }
 
+// Address returns the block type and labels joined by dots, such as
+// "resource.aws_instance.web" or "locals".
+func (b *Block) Address() string {
+	return strings.Join(append([]string{b.Type}, b.Labels...), ".")
+}
+
+// ContentHash identifies a block by its type and raw body, ignoring
+// whitespace so that reformatting does not change it. Blocks without labels,
+// such as locals, terraform or moved, all share their address and are told
+// apart by their content instead.
+func (b *Block) ContentHash() string {
+	body := strings.Join(strings.Fields(b.RawBody), "")
+	sum := sha256.Sum256([]byte(b.Type + "\x00" + body))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// FileFormat describes how a file is laid out on disk.
+type FileFormat struct {
+	CRLF bool        // Lines end with \r\n
+	BOM  bool        // Content starts with a UTF-8 byte order mark
+	Mode fs.FileMode // Permission bits
+}
+
 // ParsedFile represents a parsed Terraform file containing a collection of blocks.
 type ParsedFile struct {
-	FileName string   // Source file name
-	Blocks   []*Block // List of parsed blocks
+	FileName string     // Source file name
+	Blocks   []*Block   // List of parsed blocks
+	Format   FileFormat // On-disk layout of the source file
+	Header   string     // File-level comment block, such as a license banner
 }
 
 // ParsedFiles represents a collection of parsed Terraform files.
@@ -44,6 +76,15 @@ func (pf *ParsedFiles) FileNames() []string {
 	return names
 }
 
+// Formats returns the on-disk layout of each source file.
+func (pf *ParsedFiles) Formats() []FileFormat {
+	formats := make([]FileFormat, 0, len(pf.Files))
+	for _, file := range pf.Files {
+		formats = append(formats, file.Format)
+	}
+	return formats
+}
+
 // TotalBlocks returns the total number of blocks across all files.
 func (pf *ParsedFiles) TotalBlocks() int {
 	total := 0
@@ -55,8 +96,28 @@ func (pf *ParsedFiles) TotalBlocks() int {
 
 // BlockGroup represents a group of blocks that will be written to the same output file.
 type BlockGroup struct {
-	BlockType string   // Block type (basis for grouping)
+	BlockType string   // Block type (basis for grouping); empty if the group holds several types
 	SubType   string   // Sub-type (resource type, etc.)
 	Blocks    []*Block // Blocks included in the group
 	FileName  string   // Output file name
 }
+
+// FileAction is the kind of change made to a file.
+type FileAction string
+
+// File actions reported in FileOperation.
+const (
+	ActionCreate FileAction = "create" // a new output file is written
+	ActionUpdate FileAction = "update" // an existing output file is rewritten
+	ActionRemove FileAction = "remove" // a source or stale generated file is deleted
+	ActionBackup FileAction = "backup" // a source or stale generated file is moved to Target
+	ActionMove   FileAction = "move"   // a source file is moved to Target with git mv
+)
+
+// FileOperation describes a change made to a file, or planned in dry-run mode.
+type FileOperation struct {
+	Action  FileAction // Kind of change
+	Path    string     // File being changed
+	Target  string     // Destination of a backup or move
+	Content []byte     // Content written to a created or updated file
+}